@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// expiryLimits are the site-wide rules for how long snippets may last, set by
+// command-line flags.
+type expiryLimits struct {
+	Min        time.Duration
+	Max        time.Duration
+	AllowNever bool
+}
+
+// defaultExpiryLimits match the flag defaults in main().
+var defaultExpiryLimits = expiryLimits{
+	Min:        time.Hour,
+	Max:        365 * 24 * time.Hour,
+	AllowNever: true,
+}
+
+// The expiry choices on the snippet forms, besides a number of days. Custom
+// lasts a number of hours or days typed in by the user, and date lasts until
+// the start of a day picked from a calendar. Keep is only offered when
+// editing, and leaves the snippet's expiry as it was.
+const (
+	expiresBurn   = "burn"
+	expiresNever  = "never"
+	expiresCustom = "custom"
+	expiresDate   = "date"
+	expiresKeep   = "keep"
+)
+
+// burnExpiry is how long a burn-after-reading snippet lasts if it's never
+// read, unless the site maximum is shorter.
+const burnExpiry = 7 * 24 * time.Hour
+
+// checkExpiry validates the expiry fields of the form against the limits and
+// works out when the snippet should expire, relative to now. The zero time
+// means never.
+func (form *snippetCreateForm) checkExpiry(limits expiryLimits, now time.Time) time.Time {
+	var expires time.Time
+
+	switch form.Expires {
+	case expiresBurn:
+		return now.Add(min(burnExpiry, limits.Max))
+
+	case expiresNever:
+		form.CheckField(limits.AllowNever, "expires", "Snippets must have an expiry date")
+		return time.Time{}
+
+	case expiresCustom:
+		var unit time.Duration
+		switch form.ExpiresUnit {
+		case "hours":
+			unit = time.Hour
+		case "days":
+			unit = 24 * time.Hour
+		default:
+			form.AddFieldError("expires", "This field must be a number of hours or days")
+			return time.Time{}
+		}
+		if form.ExpiresAmount < 1 {
+			form.AddFieldError("expires", "This field must be a whole number greater than zero")
+			return time.Time{}
+		}
+		// Avoid overflowing time.Duration with silly numbers; anything that
+		// big is over the limit anyway.
+		expires = now.Add(time.Duration(min(form.ExpiresAmount, 100_000)) * unit)
+
+	case expiresDate:
+		date, err := time.Parse("2006-01-02", form.ExpiresDate)
+		if err != nil {
+			form.AddFieldError("expires", "This field must be a valid date")
+			return time.Time{}
+		}
+		expires = date
+
+	default:
+		days, err := strconv.Atoi(form.Expires)
+		if err != nil || days < 1 {
+			form.AddFieldError("expires", "Please choose when the snippet should expire")
+			return time.Time{}
+		}
+		expires = now.AddDate(0, 0, days)
+	}
+
+	form.CheckField(!expires.Before(now.Add(limits.Min)), "expires", fmt.Sprintf("This field must be at least %s from now", formatDuration(limits.Min)))
+	form.CheckField(!expires.After(now.Add(limits.Max)), "expires", fmt.Sprintf("This field must be no more than %s from now", formatDuration(limits.Max)))
+
+	return expires
+}
+
+// Burn reports whether burn after reading was chosen instead of an expiry.
+func (form snippetCreateForm) Burn() bool {
+	return form.Expires == expiresBurn
+}
+
+// formatDuration describes a duration in whole days or hours, for use in
+// validation messages.
+func formatDuration(d time.Duration) string {
+	plural := func(n int64, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s", unit)
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+
+	if d >= 24*time.Hour && d%(24*time.Hour) == 0 {
+		return plural(int64(d/(24*time.Hour)), "day")
+	}
+	if d >= time.Hour && d%time.Hour == 0 {
+		return plural(int64(d/time.Hour), "hour")
+	}
+	return d.String()
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Baytancha/snip56/internal/assert"
+)
+
+func TestCheckExpiry(t *testing.T) {
+	now := time.Date(2024, 3, 17, 10, 15, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		form    snippetCreateForm
+		limits  expiryLimits
+		want    time.Time
+		wantErr string
+	}{
+		{
+			name: "Days",
+			form: snippetCreateForm{Expires: "7"},
+			want: time.Date(2024, 3, 24, 10, 15, 0, 0, time.UTC),
+		},
+		{
+			name: "Never",
+			form: snippetCreateForm{Expires: "never"},
+			want: time.Time{},
+		},
+		{
+			name:    "Never not allowed",
+			form:    snippetCreateForm{Expires: "never"},
+			limits:  expiryLimits{Min: time.Hour, Max: 24 * time.Hour},
+			wantErr: "Snippets must have an expiry date",
+		},
+		{
+			name: "Burn",
+			form: snippetCreateForm{Expires: "burn"},
+			want: time.Date(2024, 3, 24, 10, 15, 0, 0, time.UTC),
+		},
+		{
+			name:   "Burn with short maximum",
+			form:   snippetCreateForm{Expires: "burn"},
+			limits: expiryLimits{Min: time.Hour, Max: 24 * time.Hour},
+			want:   time.Date(2024, 3, 18, 10, 15, 0, 0, time.UTC),
+		},
+		{
+			name: "Hours",
+			form: snippetCreateForm{Expires: "custom", ExpiresAmount: 6, ExpiresUnit: "hours"},
+			want: time.Date(2024, 3, 17, 16, 15, 0, 0, time.UTC),
+		},
+		{
+			name: "Custom days",
+			form: snippetCreateForm{Expires: "custom", ExpiresAmount: 30, ExpiresUnit: "days"},
+			want: time.Date(2024, 4, 16, 10, 15, 0, 0, time.UTC),
+		},
+		{
+			name:    "Bad unit",
+			form:    snippetCreateForm{Expires: "custom", ExpiresAmount: 6, ExpiresUnit: "weeks"},
+			wantErr: "This field must be a number of hours or days",
+		},
+		{
+			name:    "Zero amount",
+			form:    snippetCreateForm{Expires: "custom", ExpiresUnit: "hours"},
+			wantErr: "This field must be a whole number greater than zero",
+		},
+		{
+			name:    "Huge amount",
+			form:    snippetCreateForm{Expires: "custom", ExpiresAmount: 1 << 50, ExpiresUnit: "days"},
+			wantErr: "This field must be no more than 365 days from now",
+		},
+		{
+			name: "Date",
+			form: snippetCreateForm{Expires: "date", ExpiresDate: "2024-05-01"},
+			want: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "Past date",
+			form:    snippetCreateForm{Expires: "date", ExpiresDate: "2024-03-17"},
+			wantErr: "This field must be at least 1 hour from now",
+		},
+		{
+			name:    "Bad date",
+			form:    snippetCreateForm{Expires: "date", ExpiresDate: "17/03/2024"},
+			wantErr: "This field must be a valid date",
+		},
+		{
+			name:    "Over the maximum",
+			form:    snippetCreateForm{Expires: "400"},
+			wantErr: "This field must be no more than 365 days from now",
+		},
+		{
+			name:    "Missing",
+			form:    snippetCreateForm{},
+			wantErr: "Please choose when the snippet should expire",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limits := tt.limits
+			if limits == (expiryLimits{}) {
+				limits = defaultExpiryLimits
+			}
+
+			got := tt.form.checkExpiry(limits, now)
+
+			assert.Equal(t, tt.form.FieldErrors["expires"], tt.wantErr)
+			if tt.wantErr == "" {
+				assert.Equal(t, got, tt.want)
+			}
+		})
+	}
+}
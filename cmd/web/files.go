@@ -0,0 +1,67 @@
+package main
+
+import (
+	"github.com/Baytancha/snip56/internal/highlight"
+	"github.com/Baytancha/snip56/internal/models"
+	"github.com/Baytancha/snip56/internal/validator"
+)
+
+// snippetFileForm is one of the extra file rows on the snippet forms. The
+// rows are posted as files[0].name, files[0].content, files[1].name and so on.
+type snippetFileForm struct {
+	Name    string `form:"name"`
+	Content string `form:"content"`
+}
+
+// checkFiles validates the file names and extra files on the form. Rows left
+// completely blank are dropped, so that the form can always offer an empty one.
+func (form *snippetCreateForm) checkFiles() {
+	var files []snippetFileForm
+	for _, f := range form.Files {
+		if validator.NotBlank(f.Name) || validator.NotBlank(f.Content) {
+			files = append(files, f)
+		}
+	}
+	form.Files = files
+
+	form.CheckField(form.Filename == "" || validator.Matches(form.Filename, validator.FileNameRX), "filename", "File names may only contain letters, digits and the characters . _ -")
+
+	if len(form.Files) == 0 {
+		return
+	}
+
+	form.CheckField(form.Filename != "", "filename", "Name the main file when a snippet has more than one")
+	form.CheckField(len(form.Files) < models.MaxFiles, "files", "A snippet cannot have more than 10 files")
+
+	seen := map[string]bool{form.Filename: true}
+	for _, f := range form.Files {
+		form.CheckField(validator.Matches(f.Name, validator.FileNameRX), "files", "File names may only contain letters, digits and the characters . _ -")
+		form.CheckField(validator.NotBlank(f.Content), "files", "Files cannot be blank")
+		form.CheckField(!seen[f.Name], "files", "Every file must have a different name")
+		seen[f.Name] = true
+	}
+}
+
+// files converts the extra file rows into the files saved by the snippet
+// model, guessing each one's language from its name and content.
+func (form snippetCreateForm) files() []models.File {
+	var files []models.File
+	for _, f := range form.Files {
+		files = append(files, models.File{
+			Name:     f.Name,
+			Language: highlight.DetectFile(f.Name, f.Content),
+			Content:  f.Content,
+		})
+	}
+	return files
+}
+
+// fileForms returns the extra files of a snippet as form rows, for pre-filling
+// the edit and fork forms.
+func fileForms(s *models.Snippet) []snippetFileForm {
+	var files []snippetFileForm
+	for _, f := range s.Files {
+		files = append(files, snippetFileForm{Name: f.Name, Content: f.Content})
+	}
+	return files
+}
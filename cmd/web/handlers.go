@@ -8,10 +8,14 @@ import (
 	// New import
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	//"strings"      // New import
 	//"unicode/utf8" // New import
 
+	"github.com/Baytancha/snip56/internal/diff"
+	"github.com/Baytancha/snip56/internal/highlight"
 	"github.com/Baytancha/snip56/internal/models"
 	"github.com/Baytancha/snip56/internal/validator"
 	"github.com/julienschmidt/httprouter" // New import
@@ -29,12 +33,189 @@ import (
 // input with the name "title" in the Title field. The struct tag `form:"-"`
 // tells the decoder to completely ignore a field during decoding.
 type snippetCreateForm struct {
+	Title               string            `form:"title"`
+	Filename            string            `form:"filename"`
+	Content             string            `form:"content"`
+	Files               []snippetFileForm `form:"files"`
+	Expires             string            `form:"expires"` // days, or one of the expires* choices
+	ExpiresAmount       int               `form:"expires_amount"`
+	ExpiresUnit         string            `form:"expires_unit"`
+	ExpiresDate         string            `form:"expires_date"`
+	Language            string            `form:"language"`
+	Format              string            `form:"format"`
+	Visibility          string            `form:"visibility"`
+	Password            string            `form:"password"`
+	ClearPassword       bool              `form:"clear_password"`
+	Tags                string            `form:"tags"`
+	Parent              string            `form:"parent"` // public ID of the snippet being forked
+	validator.Validator `form:"-"`
+}
+
+// input converts the form into the values saved by the snippet model, given the
+// expiry worked out by checkExpiry(). A snippet whose language was left blank
+// gets one guessed from its file name and content, and snippets are public
+// code unless the form says otherwise. Plain text and Markdown snippets
+// always get the matching language, so they download with the right
+// extension.
+func (form snippetCreateForm) input(expires time.Time) models.SnippetInput {
+	format := form.Format
+	if format == "" {
+		format = models.FormatCode
+	}
+
+	language := form.Language
+	switch {
+	case format == models.FormatPlain:
+		language = "plaintext"
+	case format == models.FormatMarkdown:
+		language = "markdown"
+	case language == "":
+		language = highlight.DetectFile(form.Filename, form.Content)
+	}
+
+	visibility := form.Visibility
+	if visibility == "" {
+		visibility = models.VisibilityPublic
+	}
+
+	return models.SnippetInput{
+		Title:         form.Title,
+		Filename:      form.Filename,
+		Content:       form.Content,
+		Language:      language,
+		Format:        format,
+		Files:         form.files(),
+		Tags:          splitTags(form.Tags),
+		Visibility:    visibility,
+		Password:      form.Password,
+		ClearPassword: form.ClearPassword,
+		Burn:          form.Burn(),
+		Expires:       expires,
+		KeepExpiry:    form.Expires == expiresKeep,
+	}
+}
+
+// validate checks the fields shared by the create and edit forms, up to a
+// total size of maxBytes. The expiry is left to the caller, as editing can keep
+// the current one.
+func (form *snippetCreateForm) validate(maxBytes int) {
+	form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cannot be more than 100 characters long")
+	form.CheckField(validator.NotBlank(form.Content), "content", "This field cannot be blank")
+	form.checkFiles()
+	form.checkSize(maxBytes)
+
+	tags := splitTags(form.Tags)
+	form.CheckField(validator.AllMatch(tags, validator.TagRX), "tags", "Tags may only contain lowercase letters, digits and the characters + # . -")
+	form.CheckField(validator.MaxItems(tags, 10), "tags", "This field cannot have more than 10 tags")
+	form.CheckField(form.Language == "" || validator.PermittedValue(form.Language, highlight.Names()...), "language", "This field must be one of the listed languages")
+	form.CheckField(validator.PermittedValue(form.Format, "", models.FormatPlain, models.FormatCode, models.FormatMarkdown), "format", "This field must be plain text, code or Markdown")
+	form.CheckField(validator.PermittedValue(form.Visibility, "", models.VisibilityPublic, models.VisibilityUnlisted, models.VisibilityPrivate), "visibility", "This field must be public, unlisted or private")
+	form.CheckField(form.Password == "" || validator.MinChars(form.Password, 8), "password", "This field must be at least 8 characters long")
+}
+
+// snippetUnlockForm holds the password entered to see a protected snippet.
+type snippetUnlockForm struct {
+	Password            string `form:"password"`
+	validator.Validator `form:"-"`
+}
+
+// commentForm holds a new comment, or a reply to the comment ParentID.
+type commentForm struct {
+	Body                string `form:"body"`
+	ParentID            int    `form:"parent_id"`
+	validator.Validator `form:"-"`
+}
+
+// collectionForm holds the editable fields of a collection.
+type collectionForm struct {
+	Name                string `form:"name"`
+	Description         string `form:"description"`
+	Visibility          string `form:"visibility"`
+	validator.Validator `form:"-"`
+}
+
+// validate checks the fields shared by the create and edit forms.
+func (form *collectionForm) validate() {
+	form.CheckField(validator.NotBlank(form.Name), "name", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Name, 100), "name", "This field cannot be more than 100 characters long")
+	form.CheckField(validator.MaxChars(form.Description, 1000), "description", "This field cannot be more than 1000 characters long")
+	form.CheckField(validator.PermittedValue(form.Visibility, models.VisibilityPublic, models.VisibilityUnlisted, models.VisibilityPrivate), "visibility", "This field must be public, unlisted or private")
+}
+
+func (form collectionForm) input() models.CollectionInput {
+	return models.CollectionInput{
+		Name:        form.Name,
+		Description: form.Description,
+		Visibility:  form.Visibility,
+	}
+}
+
+// templateForm holds the fields of a snippet template. SiteWide is only
+// offered to admins, when creating a template.
+type templateForm struct {
+	Name                string `form:"name"`
 	Title               string `form:"title"`
 	Content             string `form:"content"`
-	Expires             int    `form:"expires"`
+	Language            string `form:"language"`
+	Format              string `form:"format"`
+	Expires             string `form:"expires"`
+	ExpiresAmount       int    `form:"expires_amount"`
+	ExpiresUnit         string `form:"expires_unit"`
+	SiteWide            bool   `form:"site_wide"`
 	validator.Validator `form:"-"`
 }
 
+// validate checks the fields shared by the create and edit forms. A template's
+// expiry is relative to when a snippet is made from it, so it can't be a fixed
+// date, but otherwise it follows the same rules as a snippet's.
+func (form *templateForm) validate(limits expiryLimits, maxBytes int) {
+	form.CheckField(validator.NotBlank(form.Name), "name", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Name, 100), "name", "This field cannot be more than 100 characters long")
+	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cannot be more than 100 characters long")
+	form.CheckField(validator.NotBlank(form.Content), "content", "This field cannot be blank")
+	form.CheckField(validator.MaxBytes(form.Content, maxBytes), "content", fmt.Sprintf("This field cannot be more than %s", formatSize(maxBytes)))
+	form.CheckField(form.Language == "" || validator.PermittedValue(form.Language, highlight.Names()...), "language", "This field must be one of the listed languages")
+	form.CheckField(validator.PermittedValue(form.Format, models.FormatPlain, models.FormatCode, models.FormatMarkdown), "format", "This field must be plain text, code or Markdown")
+
+	if form.Expires == expiresDate {
+		form.AddFieldError("expires", "Templates can't expire on a fixed date")
+		return
+	}
+
+	expiry := snippetCreateForm{
+		Expires:       form.Expires,
+		ExpiresAmount: form.ExpiresAmount,
+		ExpiresUnit:   form.ExpiresUnit,
+	}
+	expiry.checkExpiry(limits, time.Now().UTC())
+	if msg, ok := expiry.FieldErrors["expires"]; ok {
+		form.AddFieldError("expires", msg)
+	}
+}
+
+func (form templateForm) input() models.TemplateInput {
+	return models.TemplateInput{
+		Name:          form.Name,
+		Title:         form.Title,
+		Content:       form.Content,
+		Language:      form.Language,
+		Format:        form.Format,
+		Expires:       form.Expires,
+		ExpiresAmount: form.ExpiresAmount,
+		ExpiresUnit:   form.ExpiresUnit,
+	}
+}
+
+// collectionSnippetForm picks a snippet to add to, remove from or move within
+// a collection. Snippets are added by public ID, and removed or moved by their
+// ID. Direction is only used when moving, and is "up" or "down".
+type collectionSnippetForm struct {
+	Snippet   string `form:"snippet"`
+	SnippetID int    `form:"snippet_id"`
+	Direction string `form:"direction"`
+}
+
 // Create a new userSignupForm struct.
 type userSignupForm struct {
 	Name                string `form:"name"`
@@ -63,9 +244,7 @@ func (app *application) userSignupPost(w http.ResponseWriter, r *http.Request) {
 	var form userSignupForm
 
 	// Parse the form data into the userSignupForm struct.
-	err := app.decodePostForm(r, &form)
-	if err != nil {
-		app.clientError(w, http.StatusBadRequest)
+	if !app.decodePostForm(w, r, &form) {
 		return
 	}
 	fmt.Println("CHECKING?")
@@ -86,7 +265,7 @@ func (app *application) userSignupPost(w http.ResponseWriter, r *http.Request) {
 	}
 	// Try to create a new user record in the database. If the email already
 	// exists then add an error message to the form and re-display it.
-	err = app.users.Insert(form.Name, form.Email, form.Password)
+	err := app.users.Insert(form.Name, form.Email, form.Password)
 	if err != nil {
 		if errors.Is(err, models.ErrDuplicateEmail) {
 			// see {{with .Form.FieldErrors.email}} in the HTML file
@@ -124,9 +303,7 @@ func (app *application) userLoginPost(w http.ResponseWriter, r *http.Request) {
 	// Decode the form data into the userLoginForm struct.
 	var form userLoginForm
 
-	err := app.decodePostForm(r, &form)
-	if err != nil {
-		app.clientError(w, http.StatusBadRequest)
+	if !app.decodePostForm(w, r, &form) {
 		return
 	}
 
@@ -218,7 +395,22 @@ func (app *application) home(w http.ResponseWriter, r *http.Request) {
 
 	//panic("oops! something went wrong") // Deliberate panic
 
-	snippets, err := app.snippets.Latest()
+	// The latest snippets are listed unless ?sort=stars asks for the ones
+	// starred most over the last week instead.
+	sort := r.URL.Query().Get("sort")
+
+	var snippets []*models.Snippet
+	var err error
+
+	switch sort {
+	case "":
+		snippets, err = app.snippets.Latest()
+	case "stars":
+		snippets, err = app.snippets.MostStarred(time.Now().Add(-7*24*time.Hour), 10)
+	default:
+		app.notFound(w)
+		return
+	}
 	if err != nil {
 		app.serverError(w, err)
 		return
@@ -233,8 +425,16 @@ func (app *application) home(w http.ResponseWriter, r *http.Request) {
 	// Call the newTemplateData() helper to get a templateData struct containing
 	// the 'default' data (which for now is just the current year), and add the
 	// snippets slice to it.
+	tags, err := app.snippets.TagCounts(50)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
 	data := app.newTemplateData(r)
 	data.Snippets = snippets
+	data.Sort = sort
+	data.TagCloud = newTagCloud(tags)
 
 	app.render(w, http.StatusOK, "home.page.tmpl", data)
 
@@ -307,6 +507,107 @@ func (app *application) home(w http.ResponseWriter, r *http.Request) {
 	//w.Write([]byte("Hello from Snippetbox"))
 }
 
+// The snippetList handler shows every live snippet, newest first, one page at a
+// time. The page number comes from the "page" query string parameter.
+func (app *application) snippetList(w http.ResponseWriter, r *http.Request) {
+	page, ok := readPage(r.URL.Query())
+	if !ok {
+		app.notFound(w)
+		return
+	}
+
+	snippets, total, err := app.snippets.List(models.SnippetFilter{}, page)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	p := newPagination(r.URL, page, models.SnippetPageSize, total)
+	if page > p.LastPage() {
+		app.notFound(w)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippets = snippets
+	data.Pagination = p
+
+	app.render(w, http.StatusOK, "list.tmpl", data)
+}
+
+// The tagList handler shows the live snippets with a given tag, paginated in
+// the same way as snippetList.
+func (app *application) tagList(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	tag := params.ByName("name")
+	if !validator.Matches(tag, validator.TagRX) {
+		app.notFound(w)
+		return
+	}
+
+	page, ok := readPage(r.URL.Query())
+	if !ok {
+		app.notFound(w)
+		return
+	}
+
+	snippets, total, err := app.snippets.List(models.SnippetFilter{Tag: tag}, page)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	p := newPagination(r.URL, page, models.SnippetPageSize, total)
+	if total == 0 || page > p.LastPage() {
+		app.notFound(w)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippets = snippets
+	data.Pagination = p
+	data.Tag = tag
+
+	app.render(w, http.StatusOK, "tag.tmpl", data)
+}
+
+// The search handler runs a full-text search over snippet titles and content.
+// The query string parameter "q" accepts words, "quoted phrases" and -excluded
+// words; results are ranked by relevance and paginated like snippetList.
+func (app *application) search(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	page, ok := readPage(qs)
+	if !ok {
+		app.notFound(w)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Search = qs.Get("q")
+
+	// Without a search term just show the empty search form.
+	if !validator.NotBlank(data.Search) {
+		app.render(w, http.StatusOK, "search.tmpl", data)
+		return
+	}
+
+	query := models.ParseSearchQuery(data.Search)
+
+	snippets, total, err := app.snippets.Search(query, page)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data.Snippets = snippets
+	data.SearchTerms = query.Terms
+	data.Pagination = newPagination(r.URL, page, models.SnippetPageSize, total)
+
+	app.render(w, http.StatusOK, "search.tmpl", data)
+}
+
 func (app *application) about(w http.ResponseWriter, r *http.Request) {
 
 	// if r.URL.Path != "/" { //restricting the wildcard pattern
@@ -375,6 +676,63 @@ func (app *application) accountView(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// The userProfile handler shows the public details of a snippet author, their
+// star counts and a page of their public snippets. Unlike accountView it is
+// reachable by anyone, so it must never expose private data such as the
+// user's email address.
+func (app *application) userProfile(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	page, ok := readPage(r.URL.Query())
+	if !ok {
+		app.notFound(w)
+		return
+	}
+
+	user, err := app.users.GetbyID(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	// Only the user's public snippets are listed, whoever is looking.
+	snippets, total, err := app.snippets.List(models.SnippetFilter{UserID: user.ID}, page)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	p := newPagination(r.URL, page, models.SnippetPageSize, total)
+	if page > p.LastPage() {
+		app.notFound(w)
+		return
+	}
+
+	received, given, err := app.snippets.StarCounts(user.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.User = user
+	data.Snippets = snippets
+	data.Pagination = p
+	data.StarsReceived = received
+	data.StarsGiven = given
+	app.render(w, http.StatusOK, "profile.tmpl", data)
+}
+
 // Initialize a slice containing the paths to the view.tmpl file,
 // plus the base layout and navigation partial that we made earlier.
 // files := []string{
@@ -430,33 +788,39 @@ func (app *application) showSnippet(w http.ResponseWriter, r *http.Request) {
 	// parameter names and values like so:
 	params := httprouter.ParamsFromContext(r.Context())
 
-	// We can then use the ByName() method to get the value of the "id" named
-	// parameter from the slice and validate it as normal.
-	id, err := strconv.Atoi(params.ByName("id"))
-	if err != nil || id < 1 {
+	// Snippet pages are only found by public ID. The slug after it, if any,
+	// is ignored, so links keep working when a snippet is renamed.
+	pid := params.ByName("pid")
+	if !models.PublicIDRX.MatchString(pid) {
 		app.notFound(w)
 		return
 	}
-	// id, err := strconv.Atoi(r.URL.Query().Get("id"))
-	// if err != nil || id < 1 {
-	// 	app.notFound(w) // Use the notFound() helper.
-	// 	//http.NotFound(w, r)
-	// 	return
-	// }
 
-	// Use the SnippetModel object's Get method to retrieve the data for a
-	// specific record based on its ID. If no matching record is found,
-	// return a 404 Not Found response.
-	snippet, err := app.snippets.Get(id)
-	if err != nil {
-		if errors.Is(err, models.ErrNoRecord) {
-			app.notFound(w)
-		} else {
-			app.serverError(w, err)
-		}
+	snippet, ok := app.lookupSnippet(w, r, pid)
+	if !ok {
+		return
+	}
+
+	// Ask for the password instead if the snippet is protected.
+	if !app.unlocked(r, snippet) {
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = snippetUnlockForm{}
+		app.render(w, http.StatusOK, "unlock.tmpl", data)
+		return
+	}
+
+	// Reading a burn-after-reading snippet deletes it.
+	snippet, ok = app.burn(w, r, snippet)
+	if !ok {
 		return
 	}
 
+	// A burnt snippet is gone, so there's no point counting its views.
+	if !snippet.Burn {
+		app.countView(r, snippet)
+	}
+
 	// Initialize a slice containing the paths to the view.tmpl file,
 	// plus the base layout and navigation partial that we made earlier.
 	// files := []string{
@@ -487,12 +851,8 @@ func (app *application) showSnippet(w http.ResponseWriter, r *http.Request) {
 	// data this will return the empty string.
 	//flash := app.sessionManager.PopString(r.Context(), "flash")
 
-	// And do the same thing again here...
-	data := app.newTemplateData(r)
-	data.Snippet = snippet
-	// Pass the flash message to the template.
-
-	app.render(w, http.StatusOK, "view.tmpl", data)
+	// Show the snippet along with its forks and comments.
+	app.renderSnippet(w, r, http.StatusOK, snippet, commentForm{})
 
 	//при использовании ExecuteTemplate не нужно собирать вложенные шаблоны и соблюдать порядок вызова шаблонов
 
@@ -507,68 +867,230 @@ func (app *application) showSnippet(w http.ResponseWriter, r *http.Request) {
 	//fmt.Fprintf(w, "Display a specific snippet with ID %d...", id)
 }
 
-// Add a new snippetCreate handler, which for now returns a placeholder
-// response. We'll update this shortly to show a HTML form.
-func (app *application) createSnippet(w http.ResponseWriter, r *http.Request) {
-	//w.Write([]byte("Display the form for creating a new snippet..."))
+// The snippetViewRedirect handler sends old /snippet/view/:id links on to the
+// snippet's page.
+func (app *application) snippetViewRedirect(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.paramSnippet(w, r)
+	if !ok {
+		return
+	}
 
-	data := app.newTemplateData(r)
+	http.Redirect(w, r, snippetURL(snippet), http.StatusMovedPermanently)
+}
 
-	// Initialize a new createSnippetForm instance and pass it to the template.
-	// Notice how this is also a great opportunity to set any default or
-	// 'initial' values for the form --- here we set the initial value for the
-	// snippet expiry to 365 days.
-	// data.Form = snippetCreateForm{
-	// 	Expires: 365,
-	// }
+// The unlockSnippetPost handler checks the password for a protected snippet
+// and, if it's right, remembers in the session that the snippet is unlocked.
+// Wrong guesses are rate limited per snippet, so that a password can't be
+// found by trying lots of them.
+func (app *application) unlockSnippetPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.paramSnippet(w, r)
+	if !ok {
+		return
+	}
 
-	app.render(w, http.StatusOK, "create.tmpl", data)
+	if app.unlocked(r, snippet) {
+		http.Redirect(w, r, snippetURL(snippet), http.StatusSeeOther)
+		return
+	}
 
-}
+	var form snippetUnlockForm
 
-// Когда мы закончили заполнять форму мы нажимаем на кнопку и получаем POST-запрос с URL
-// Все это зашито в HTML-файл, как только файл генерит URL-запрос, сервер его обрабатывает
-// и вызывает функцию createSnippetPost
-func (app *application) createSnippetPost(w http.ResponseWriter, r *http.Request) {
+	if !app.decodePostForm(w, r, &form) {
+		return
+	}
 
-	// Checking if the request method is a POST is now superfluous and can be
-	// removed, because this is done automatically by httprouter.
-	// if r.Method != "POST" {
-	// 	w.Header().Set("Allow", "POST")
-	// 	app.clientError(w, http.StatusMethodNotAllowed) // Use the clientError() helper.
-	// 	//http.Error(w, "Method Not Allowed", 405)
-	// 	return
-	// }
-	// Create some variables holding dummy data. We'll remove these later on
-	// during the build.
+	if !app.unlockLimiter.Reserve(snippet.ID) {
+		form.AddNonFieldError("Too many wrong passwords have been tried. Please wait a few minutes and try again.")
 
-	// First we call r.ParseForm() which adds any data in POST request bodies
-	// to the r.PostForm map. This also works in the same way for PUT and PATCH
-	// requests. If there are any errors, we use our app.ClientError() helper to
-	// send a 400 Bad Request response to the user.
-	// err := r.ParseForm()
-	// if err != nil {
-	// 	app.clientError(w, http.StatusBadRequest)
-	// 	return
-	// }
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		app.render(w, http.StatusTooManyRequests, "unlock.tmpl", data)
+		return
+	}
 
-	// Use the r.PostForm.Get() method to retrieve the title and content
-	// from the r.PostForm map.
-	//title := r.PostForm.Get("title")
-	//content := r.PostForm.Get("content")
+	err := snippet.CheckPassword(form.Password)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCredentials) {
+			form.AddNonFieldError("The password is incorrect")
 
-	// The r.PostForm.Get() method always returns the form data as a *string*.
-	// However, we're expecting our expires value to be a number, and want to
-	// represent it in our Go code as an integer. So we need to manually covert
+			data := app.newTemplateData(r)
+			data.Snippet = snippet
+			data.Form = form
+			app.render(w, http.StatusUnprocessableEntity, "unlock.tmpl", data)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.unlockLimiter.Clear(snippet.ID)
+	app.sessionManager.Put(r.Context(), unlockKey(snippet.ID), true)
+
+	http.Redirect(w, r, snippetURL(snippet), http.StatusSeeOther)
+}
+
+// The snippetRaw handler sends the bare content of a snippet as plain text, so
+// that it can be fetched with curl or linked to from elsewhere.
+func (app *application) snippetRaw(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.unlockedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	snippet, ok = app.burn(w, r, snippet)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(snippet.Content))
+}
+
+// The snippetFileRaw handler sends the bare content of one file of a
+// multi-file snippet as plain text.
+func (app *application) snippetFileRaw(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.unlockedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	file := snippet.File(httprouter.ParamsFromContext(r.Context()).ByName("name"))
+	if file == nil {
+		app.notFound(w)
+		return
+	}
+
+	_, ok = app.burn(w, r, snippet)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(file.Content))
+}
+
+// The snippetDownload handler sends the content of a snippet as an attachment,
+// named after its title and language.
+func (app *application) snippetDownload(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.unlockedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	snippet, ok = app.burn(w, r, snippet)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", downloadName(snippet)))
+	w.Write([]byte(snippet.Content))
+}
+
+// Add a new snippetCreate handler, which for now returns a placeholder
+// response. We'll update this shortly to show a HTML form.
+func (app *application) createSnippet(w http.ResponseWriter, r *http.Request) {
+	//w.Write([]byte("Display the form for creating a new snippet..."))
+
+	userID := app.authenticatedUserID(r)
+
+	templates, err := app.templates.ForUser(userID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Templates = templates
+
+	// A template picked from the list fills in the form, which can still be
+	// changed before publishing.
+	if key := r.URL.Query().Get("template"); key != "" {
+		id, err := strconv.Atoi(key)
+		if err != nil || id < 1 {
+			app.notFound(w)
+			return
+		}
+
+		t, err := app.templates.Get(id, userID)
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				app.notFound(w)
+			} else {
+				app.serverError(w, err)
+			}
+			return
+		}
+
+		data.Form = snippetCreateForm{
+			Title:         t.Title,
+			Content:       t.Content,
+			Expires:       t.Expires,
+			ExpiresAmount: t.ExpiresAmount,
+			ExpiresUnit:   t.ExpiresUnit,
+			Language:      t.Language,
+			Format:        t.Format,
+			Visibility:    models.VisibilityPublic,
+		}
+
+		app.render(w, http.StatusOK, "redisplay.tmpl", data)
+		return
+	}
+
+	// Initialize a new createSnippetForm instance and pass it to the template.
+	// Notice how this is also a great opportunity to set any default or
+	// 'initial' values for the form --- here we set the initial value for the
+	// snippet expiry to 365 days.
+	// data.Form = snippetCreateForm{
+	// 	Expires: 365,
+	// }
+
+	app.render(w, http.StatusOK, "create.tmpl", data)
+
+}
+
+// Когда мы закончили заполнять форму мы нажимаем на кнопку и получаем POST-запрос с URL
+// Все это зашито в HTML-файл, как только файл генерит URL-запрос, сервер его обрабатывает
+// и вызывает функцию createSnippetPost
+func (app *application) createSnippetPost(w http.ResponseWriter, r *http.Request) {
+
+	// Checking if the request method is a POST is now superfluous and can be
+	// removed, because this is done automatically by httprouter.
+	// if r.Method != "POST" {
+	// 	w.Header().Set("Allow", "POST")
+	// 	app.clientError(w, http.StatusMethodNotAllowed) // Use the clientError() helper.
+	// 	//http.Error(w, "Method Not Allowed", 405)
+	// 	return
+	// }
+	// Create some variables holding dummy data. We'll remove these later on
+	// during the build.
+
+	// First we call r.ParseForm() which adds any data in POST request bodies
+	// to the r.PostForm map. This also works in the same way for PUT and PATCH
+	// requests. If there are any errors, we use our app.ClientError() helper to
+	// send a 400 Bad Request response to the user.
+	// err := r.ParseForm()
+	// if err != nil {
+	// 	app.clientError(w, http.StatusBadRequest)
+	// 	return
+	// }
+
+	// Use the r.PostForm.Get() method to retrieve the title and content
+	// from the r.PostForm map.
+	//title := r.PostForm.Get("title")
+	//content := r.PostForm.Get("content")
+
+	// The r.PostForm.Get() method always returns the form data as a *string*.
+	// However, we're expecting our expires value to be a number, and want to
+	// represent it in our Go code as an integer. So we need to manually covert
 	// the form data to an integer using strconv.Atoi(), and we send a 400 Bad
 	// Request response if the conversion fails.
 
 	// Declare a new empty instance of the snippetCreateForm struct.
 	var form snippetCreateForm
 
-	err := app.decodePostForm(r, &form)
-	if err != nil {
-		app.clientError(w, http.StatusBadRequest)
+	if !app.decodePostForm(w, r, &form) {
 		return
 	}
 
@@ -608,10 +1130,8 @@ func (app *application) createSnippetPost(w http.ResponseWriter, r *http.Request
 
 	// Update the validation checks so that they operate on the snippetCreateForm
 	// instance.
-	form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be blank")
-	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cannot be more than 100 characters long")
-	form.CheckField(validator.NotBlank(form.Content), "content", "This field cannot be blank")
-	form.CheckField(validator.PermittedInt(form.Expires, 1, 7, 365), "expires", "This field must equal 1, 7 or 365")
+	form.validate(app.maxSnippetBytes)
+	expires := form.checkExpiry(app.expiry, time.Now().UTC())
 
 	// if strings.TrimSpace(form.Title) == "" {
 	// 	form.FieldErrors["title"] = "This field cannot be blank"
@@ -651,23 +1171,934 @@ func (app *application) createSnippetPost(w http.ResponseWriter, r *http.Request
 
 	// Pass the data to the SnippetModel.Insert() method, receiving the
 	// ID of the new record back.
-	id, err := app.snippets.Insert(form.Title, form.Content, form.Expires)
+	// The snippet is owned by whoever is currently logged in. This route sits
+	// behind requireAuthentication, so the session always holds a user ID.
+	userID := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	// Only record the parent of a fork if the user can still see it. If it
+	// has gone away since the form was filled in, the fork just becomes an
+	// ordinary snippet.
+	in := form.input(expires)
+	if form.Parent != "" {
+		parent, err := app.snippets.GetByPublicID(form.Parent, userID)
+		if err != nil && !errors.Is(err, models.ErrNoRecord) {
+			app.serverError(w, err)
+			return
+		}
+		if parent != nil && !parent.Burn && app.unlocked(r, parent) {
+			in.ParentID = parent.ID
+		}
+	}
+
+	_, publicID, err := app.snippets.Insert(userID, in)
 	//id, err := app.snippets.Insert(title, content, expires)
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
 
+	url := snippetURL(&models.Snippet{PublicID: publicID, Title: form.Title})
+
+	// Viewing a burn-after-reading snippet would delete it, so instead of
+	// redirecting, show the author the link to pass on.
+	if form.Burn() {
+		data := app.newTemplateData(r)
+		data.ShareURL = fmt.Sprintf("https://%s%s", r.Host, url)
+		app.render(w, http.StatusOK, "created.tmpl", data)
+		return
+	}
+
 	// Redirect the user to the relevant page for the snippet.
 	//Using Sprintf for fast dirty concatenations
 	//http.Redirect(w, r, fmt.Sprintf("/snippet/view?id=%d", id), http.StatusSeeOther)
 	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully created!")
 	// Update the redirect path to use the new clean URL format.
-	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", id), http.StatusSeeOther)
+	http.Redirect(w, r, url, http.StatusSeeOther)
 
 	//w.Write([]byte("Create a new snippet..."))
 }
 
+// The forkSnippet handler displays the create form pre-filled with a copy of
+// any snippet the user can see. Saving it creates a new snippet owned by them,
+// which remembers where it came from.
+func (app *application) forkSnippet(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.unlockedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	// Forking a burn-after-reading snippet would show its content without
+	// burning it.
+	if snippet.Burn {
+		app.notFound(w)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Form = snippetCreateForm{
+		Title:      snippet.Title,
+		Filename:   snippet.Filename,
+		Content:    snippet.Content,
+		Files:      fileForms(snippet),
+		Expires:    "365",
+		Language:   snippet.Language,
+		Format:     snippet.Format,
+		Visibility: models.VisibilityPublic,
+		Tags:       strings.Join(snippet.Tags, " "),
+		Parent:     snippet.PublicID,
+	}
+
+	app.render(w, http.StatusOK, "redisplay.tmpl", data)
+}
+
+// The editSnippet handler displays the create form pre-filled with an existing
+// snippet. Only the snippet's author may see it.
+func (app *application) editSnippet(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Form = snippetCreateForm{
+		Title:      snippet.Title,
+		Filename:   snippet.Filename,
+		Content:    snippet.Content,
+		Files:      fileForms(snippet),
+		Expires:    expiresKeep,
+		Language:   snippet.Language,
+		Format:     snippet.Format,
+		Visibility: snippet.Visibility,
+		Tags:       strings.Join(snippet.Tags, " "),
+	}
+
+	app.render(w, http.StatusOK, "edit.tmpl", data)
+}
+
+// The editSnippetPost handler validates the edited snippet in exactly the same
+// way as createSnippetPost and then saves the changes.
+func (app *application) editSnippetPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	var form snippetCreateForm
+
+	if !app.decodePostForm(w, r, &form) {
+		return
+	}
+
+	form.validate(app.maxSnippetBytes)
+
+	// Keeping the current expiry needs no checks: the snippet is still live,
+	// so it was fine when it was set.
+	var expires time.Time
+	if form.Expires != expiresKeep {
+		expires = form.checkExpiry(app.expiry, time.Now().UTC())
+	}
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "edit.tmpl", data)
+		return
+	}
+
+	err := app.snippets.Update(snippet.ID, form.input(expires))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully updated!")
+
+	http.Redirect(w, r, snippetURL(snippet), http.StatusSeeOther)
+}
+
+// The snippetHistory handler lists the earlier versions of a snippet, with a
+// form for picking two of them to compare.
+func (app *application) snippetHistory(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.unlockedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	// A burn-after-reading snippet has no history worth showing, and showing
+	// it would give the content away without burning it.
+	if snippet.Burn {
+		app.notFound(w)
+		return
+	}
+
+	revisions, err := app.snippets.Revisions(snippet.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Revisions = revisions
+
+	app.render(w, http.StatusOK, "history.tmpl", data)
+}
+
+// The snippetDiff handler compares two versions of a snippet, given by the
+// "from" and "to" query string parameters. Revision 0 stands for the current
+// version. Adding view=split shows the versions side by side instead of as a
+// unified diff.
+func (app *application) snippetDiff(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.unlockedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	// A burn-after-reading snippet has no history worth showing, and showing
+	// it would give the content away without burning it.
+	if snippet.Burn {
+		app.notFound(w)
+		return
+	}
+
+	qs := r.URL.Query()
+
+	var versions [2]*models.Revision
+	for i, key := range []string{"from", "to"} {
+		revisionID, err := strconv.Atoi(qs.Get(key))
+		if err != nil || revisionID < 0 {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+
+		if revisionID == 0 {
+			versions[i] = &models.Revision{
+				SnippetID: snippet.ID,
+				Title:     snippet.Title,
+				Filename:  snippet.Filename,
+				Content:   snippet.Content,
+				Files:     snippet.Files,
+				Created:   snippet.Modified,
+			}
+			continue
+		}
+
+		versions[i], err = app.snippets.Revision(snippet.ID, revisionID)
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				app.notFound(w)
+			} else {
+				app.serverError(w, err)
+			}
+			return
+		}
+	}
+
+	lines := diff.Lines(diffText(versions[0]), diffText(versions[1]))
+
+	view := &diffView{
+		From:  versions[0],
+		To:    versions[1],
+		Split: qs.Get("view") == "split",
+	}
+	if view.Split {
+		view.Rows = diff.SideBySide(lines)
+	} else {
+		view.Hunks = diff.Hunks(lines, 3)
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Diff = view
+
+	app.render(w, http.StatusOK, "compare.tmpl", data)
+}
+
+// The deleteSnippetPost handler soft-deletes one of the current user's
+// snippets. It stays in the database so that an admin can restore it.
+func (app *application) deleteSnippetPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.snippets.Delete(snippet.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully deleted!")
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// The expireSnippetPost handler makes one of the current user's snippets
+// expire right away instead of waiting for its expiry date.
+func (app *application) expireSnippetPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.snippets.Expire(snippet.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Snippet has expired!")
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// The starSnippetPost handler stars a snippet for the current user, or
+// removes their star if they had already starred it.
+func (app *application) starSnippetPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.unlockedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	// There's no point bookmarking a snippet that's gone once it's read.
+	if snippet.Burn {
+		app.notFound(w)
+		return
+	}
+
+	userID := app.authenticatedUserID(r)
+
+	starred, err := app.snippets.Starred(snippet.ID, userID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	if starred {
+		err = app.snippets.Unstar(snippet.ID, userID)
+	} else {
+		err = app.snippets.Star(snippet.ID, userID)
+	}
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, snippetURL(snippet), http.StatusSeeOther)
+}
+
+// The accountStars handler lists the snippets the current user has starred.
+func (app *application) accountStars(w http.ResponseWriter, r *http.Request) {
+	page, ok := readPage(r.URL.Query())
+	if !ok {
+		app.notFound(w)
+		return
+	}
+
+	snippets, total, err := app.snippets.Stars(app.authenticatedUserID(r), page)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	p := newPagination(r.URL, page, models.SnippetPageSize, total)
+	if page > p.LastPage() {
+		app.notFound(w)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippets = snippets
+	data.Pagination = p
+
+	app.render(w, http.StatusOK, "stars.tmpl", data)
+}
+
+// The commentSnippetPost handler adds a comment to a snippet, or a reply to
+// another comment on it.
+func (app *application) commentSnippetPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.unlockedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	// Burn-after-reading snippets can't be seen again, so there's nothing to
+	// comment on.
+	if snippet.Burn {
+		app.notFound(w)
+		return
+	}
+
+	var form commentForm
+
+	if !app.decodePostForm(w, r, &form) {
+		return
+	}
+
+	form.CheckField(validator.NotBlank(form.Body), "body", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Body, 2000), "body", "This field cannot be more than 2000 characters long")
+
+	if form.ParentID != 0 {
+		parent, err := app.comments.Get(form.ParentID)
+		if err != nil && !errors.Is(err, models.ErrNoRecord) {
+			app.serverError(w, err)
+			return
+		}
+		form.CheckField(parent != nil && parent.SnippetID == snippet.ID, "body", "The comment you replied to has been deleted")
+	}
+
+	if !form.Valid() {
+		app.renderSnippet(w, r, http.StatusUnprocessableEntity, snippet, form)
+		return
+	}
+
+	id, err := app.comments.Insert(snippet.ID, app.authenticatedUserID(r), form.ParentID, form.Body)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("%s#comment-%d", snippetURL(snippet), id), http.StatusSeeOther)
+}
+
+// The deleteCommentPost handler deletes a comment. Comments can be deleted by
+// whoever wrote them and by the owner of the snippet they're on.
+func (app *application) deleteCommentPost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	comment, err := app.comments.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	userID := app.authenticatedUserID(r)
+
+	snippet, err := app.snippets.Get(comment.SnippetID, userID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	if comment.UserID != userID && snippet.UserID != userID {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	err = app.comments.Delete(comment.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Comment deleted")
+
+	http.Redirect(w, r, snippetURL(snippet)+"#comments", http.StatusSeeOther)
+}
+
+// The collectionView handler shows a collection and the snippets in it, in
+// the order chosen by its owner.
+func (app *application) collectionView(w http.ResponseWriter, r *http.Request) {
+	collection, ok := app.paramCollection(w, r)
+	if !ok {
+		return
+	}
+
+	snippets, err := app.collections.Snippets(collection.ID, app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Collection = collection
+	data.Snippets = snippets
+
+	app.render(w, http.StatusOK, "collection.tmpl", data)
+}
+
+// The accountCollections handler lists the current user's collections.
+func (app *application) accountCollections(w http.ResponseWriter, r *http.Request) {
+	collections, err := app.collections.ForUser(app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Collections = collections
+
+	app.render(w, http.StatusOK, "collections.tmpl", data)
+}
+
+func (app *application) createCollection(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.Form = collectionForm{Visibility: models.VisibilityPublic}
+
+	app.render(w, http.StatusOK, "collectionEdit.tmpl", data)
+}
+
+func (app *application) createCollectionPost(w http.ResponseWriter, r *http.Request) {
+	var form collectionForm
+
+	if !app.decodePostForm(w, r, &form) {
+		return
+	}
+
+	form.validate()
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "collectionEdit.tmpl", data)
+		return
+	}
+
+	id, err := app.collections.Insert(app.authenticatedUserID(r), form.input())
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Collection successfully created!")
+
+	http.Redirect(w, r, fmt.Sprintf("/collection/%d", id), http.StatusSeeOther)
+}
+
+func (app *application) editCollection(w http.ResponseWriter, r *http.Request) {
+	collection, ok := app.ownedCollection(w, r)
+	if !ok {
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Collection = collection
+	data.Form = collectionForm{
+		Name:        collection.Name,
+		Description: collection.Description,
+		Visibility:  collection.Visibility,
+	}
+
+	app.render(w, http.StatusOK, "collectionEdit.tmpl", data)
+}
+
+func (app *application) editCollectionPost(w http.ResponseWriter, r *http.Request) {
+	collection, ok := app.ownedCollection(w, r)
+	if !ok {
+		return
+	}
+
+	var form collectionForm
+
+	if !app.decodePostForm(w, r, &form) {
+		return
+	}
+
+	form.validate()
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Collection = collection
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "collectionEdit.tmpl", data)
+		return
+	}
+
+	err := app.collections.Update(collection.ID, form.input())
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Collection successfully updated!")
+
+	http.Redirect(w, r, fmt.Sprintf("/collection/%d", collection.ID), http.StatusSeeOther)
+}
+
+// The deleteCollectionPost handler deletes one of the current user's
+// collections. The snippets in it are not affected.
+func (app *application) deleteCollectionPost(w http.ResponseWriter, r *http.Request) {
+	collection, ok := app.ownedCollection(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.collections.Delete(collection.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Collection successfully deleted!")
+
+	http.Redirect(w, r, "/account/collections", http.StatusSeeOther)
+}
+
+// The collectionAddPost handler adds a snippet to one of the current user's
+// collections. It must be one of their own or a public one.
+func (app *application) collectionAddPost(w http.ResponseWriter, r *http.Request) {
+	collection, ok := app.ownedCollection(w, r)
+	if !ok {
+		return
+	}
+
+	var form collectionSnippetForm
+
+	if !app.decodePostForm(w, r, &form) {
+		return
+	}
+
+	snippet, ok := app.lookupSnippet(w, r, form.Snippet)
+	if !ok {
+		return
+	}
+
+	// A burn-after-reading snippet will be gone by the time anyone looks at
+	// the collection.
+	if snippet.Burn {
+		app.notFound(w)
+		return
+	}
+
+	// Someone else's unlisted snippet is only for those who have the link, and
+	// wouldn't be listed in the collection anyway.
+	if snippet.Visibility != models.VisibilityPublic && snippet.UserID != collection.UserID {
+		app.notFound(w)
+		return
+	}
+
+	err := app.collections.AddSnippet(collection.ID, snippet.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("Snippet added to %s", collection.Name))
+
+	http.Redirect(w, r, snippetURL(snippet), http.StatusSeeOther)
+}
+
+// The collectionRemovePost handler takes a snippet out of one of the current
+// user's collections.
+func (app *application) collectionRemovePost(w http.ResponseWriter, r *http.Request) {
+	collection, ok := app.ownedCollection(w, r)
+	if !ok {
+		return
+	}
+
+	var form collectionSnippetForm
+
+	if !app.decodePostForm(w, r, &form) {
+		return
+	}
+
+	err := app.collections.RemoveSnippet(collection.ID, form.SnippetID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/collection/%d", collection.ID), http.StatusSeeOther)
+}
+
+// The collectionMovePost handler moves a snippet one place up or down in one
+// of the current user's collections.
+func (app *application) collectionMovePost(w http.ResponseWriter, r *http.Request) {
+	collection, ok := app.ownedCollection(w, r)
+	if !ok {
+		return
+	}
+
+	var form collectionSnippetForm
+
+	if !app.decodePostForm(w, r, &form) {
+		return
+	}
+
+	if !validator.PermittedValue(form.Direction, "up", "down") {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	err := app.collections.MoveSnippet(collection.ID, form.SnippetID, form.Direction == "up")
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/collection/%d", collection.ID), http.StatusSeeOther)
+}
+
+// The accountTemplates handler lists the templates the current user can
+// start a snippet from: the site-wide ones and their own.
+func (app *application) accountTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := app.templates.ForUser(app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	admin, err := app.isAdmin(r)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Templates = templates
+	data.IsAdmin = admin
+
+	app.render(w, http.StatusOK, "templates.tmpl", data)
+}
+
+// The createTemplate handler shows the form for a new template. Given the
+// public ID of a snippet the user can read in ?snippet=, it saves that
+// snippet as a template instead of starting from scratch. Only the main
+// file is kept.
+func (app *application) createTemplate(w http.ResponseWriter, r *http.Request) {
+	form := templateForm{Format: models.FormatCode, Expires: "365"}
+
+	if key := r.URL.Query().Get("snippet"); key != "" {
+		snippet, ok := app.lookupSnippet(w, r, key)
+		if !ok {
+			return
+		}
+
+		// As with forking, copying a burn-after-reading snippet would show
+		// its content without burning it.
+		if snippet.Burn || !app.unlocked(r, snippet) {
+			app.notFound(w)
+			return
+		}
+
+		form = templateForm{
+			Name:     snippet.Title,
+			Title:    snippet.Title,
+			Content:  snippet.Content,
+			Language: snippet.Language,
+			Format:   snippet.Format,
+			Expires:  expiresChoice(snippet),
+		}
+	}
+
+	admin, err := app.isAdmin(r)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Form = form
+	data.IsAdmin = admin
+
+	app.render(w, http.StatusOK, "templateEdit.tmpl", data)
+}
+
+// The createTemplatePost handler saves a new template for the current user, or
+// a site-wide one if an admin asked for it.
+func (app *application) createTemplatePost(w http.ResponseWriter, r *http.Request) {
+	var form templateForm
+
+	if !app.decodePostForm(w, r, &form) {
+		return
+	}
+
+	admin, err := app.isAdmin(r)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	if form.SiteWide && !admin {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	form.validate(app.expiry, app.maxSnippetBytes)
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		data.IsAdmin = admin
+		app.render(w, http.StatusUnprocessableEntity, "templateEdit.tmpl", data)
+		return
+	}
+
+	userID := app.authenticatedUserID(r)
+	if form.SiteWide {
+		userID = 0
+	}
+
+	_, err = app.templates.Insert(userID, form.input())
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Template successfully created!")
+
+	http.Redirect(w, r, "/account/templates", http.StatusSeeOther)
+}
+
+func (app *application) editTemplate(w http.ResponseWriter, r *http.Request) {
+	t, ok := app.ownedTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Template = t
+	data.Form = templateForm{
+		Name:          t.Name,
+		Title:         t.Title,
+		Content:       t.Content,
+		Language:      t.Language,
+		Format:        t.Format,
+		Expires:       t.Expires,
+		ExpiresAmount: t.ExpiresAmount,
+		ExpiresUnit:   t.ExpiresUnit,
+	}
+
+	app.render(w, http.StatusOK, "templateEdit.tmpl", data)
+}
+
+// The editTemplatePost handler saves changes to a template. Who it belongs to
+// can't be changed here.
+func (app *application) editTemplatePost(w http.ResponseWriter, r *http.Request) {
+	t, ok := app.ownedTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	var form templateForm
+
+	if !app.decodePostForm(w, r, &form) {
+		return
+	}
+
+	form.validate(app.expiry, app.maxSnippetBytes)
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Template = t
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "templateEdit.tmpl", data)
+		return
+	}
+
+	err := app.templates.Update(t.ID, form.input())
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Template successfully updated!")
+
+	http.Redirect(w, r, "/account/templates", http.StatusSeeOther)
+}
+
+func (app *application) deleteTemplatePost(w http.ResponseWriter, r *http.Request) {
+	t, ok := app.ownedTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.templates.Delete(t.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Template successfully deleted!")
+
+	http.Redirect(w, r, "/account/templates", http.StatusSeeOther)
+}
+
+// The adminDeleted handler lists soft-deleted snippets for admins.
+func (app *application) adminDeleted(w http.ResponseWriter, r *http.Request) {
+	snippets, err := app.snippets.Deleted()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippets = snippets
+
+	app.render(w, http.StatusOK, "adminDeleted.tmpl", data)
+}
+
+// The adminRestorePost handler brings back a snippet deleted by mistake.
+func (app *application) adminRestorePost(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	err = app.snippets.Restore(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully restored!")
+
+	http.Redirect(w, r, "/admin/deleted", http.StatusSeeOther)
+}
+
 func ping(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("OK"))
 }
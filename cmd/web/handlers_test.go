@@ -1,11 +1,17 @@
 package main
 
 import (
+	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Baytancha/snip56/internal/assert"
+	"github.com/Baytancha/snip56/internal/models"
+	"github.com/Baytancha/snip56/internal/models/mocks"
 )
 
 // func TestPing(t *testing.T) {
@@ -113,10 +119,66 @@ func TestSnippetView(t *testing.T) {
 	}{
 		{
 			name:     "Valid ID",
-			urlPath:  "/snippet/view/1",
+			urlPath:  "/s/aB3xK9mQ",
 			wantCode: http.StatusOK,
 			wantBody: "An old silent pond...",
 		},
+		{
+			name:     "Line anchors",
+			urlPath:  "/s/aB3xK9mQ",
+			wantCode: http.StatusOK,
+			wantBody: `<a class="lnlinks" href="#L1">`,
+		},
+		{
+			name:     "Several files",
+			urlPath:  "/s/dC8gV1zP",
+			wantCode: http.StatusOK,
+			wantBody: "<a href='/snippet/raw/dC8gV1zP/compose.yaml'>Raw</a>",
+		},
+		{
+			name:     "File line anchors",
+			urlPath:  "/s/dC8gV1zP",
+			wantCode: http.StatusOK,
+			wantBody: `<a class="lnlinks" href="#compose.yaml-L1">`,
+		},
+		{
+			name:     "Markdown",
+			urlPath:  "/s/mN6jY4sL",
+			wantCode: http.StatusOK,
+			wantBody: "<h1>Release notes</h1>",
+		},
+		{
+			name:     "Markdown link",
+			urlPath:  "/s/mN6jY4sL",
+			wantCode: http.StatusOK,
+			wantBody: `<a href="https://example.com/docs" rel="nofollow">the docs</a>`,
+		},
+		{
+			name:     "Any slug",
+			urlPath:  "/s/aB3xK9mQ/a-different-title",
+			wantCode: http.StatusOK,
+			wantBody: "An old silent pond...",
+		},
+		{
+			name:     "Unlisted",
+			urlPath:  "/s/sE4kJ6yN/database-settings",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "Non-existent public ID",
+			urlPath:  "/s/hG5tR2wX",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Malformed public ID",
+			urlPath:  "/s/aB3x",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Old URL for unlisted snippet",
+			urlPath:  "/snippet/view/5",
+			wantCode: http.StatusNotFound,
+		},
 		{
 			name:     "Non-existent ID",
 			urlPath:  "/snippet/view/2",
@@ -155,6 +217,17 @@ func TestSnippetView(t *testing.T) {
 			}
 		})
 	}
+
+	t.Run("Old URL", func(t *testing.T) {
+		code, headers, _ := ts.get(t, "/snippet/view/1")
+		assert.Equal(t, code, http.StatusMovedPermanently)
+		assert.Equal(t, headers.Get("Location"), "/s/aB3xK9mQ/an-old-silent-pond")
+	})
+
+	t.Run("Markdown HTML removed", func(t *testing.T) {
+		_, _, body := ts.get(t, "/s/mN6jY4sL")
+		assert.Equal(t, strings.Contains(body, "alert(1)"), false)
+	})
 }
 
 func TestUserSignup(t *testing.T) {
@@ -310,8 +383,8 @@ func TestCreateSnippet(t *testing.T) {
 		{
 			name:         "Authenticated",
 			urlPath:      "/snippet/create",
-			userEmail:    "tommytinkrem@gmail.com",
-			userPassword: "heckfy10",
+			userEmail:    "alice@example.com",
+			userPassword: "pa$$word",
 			wantCode:     http.StatusOK,
 			wantBody:     "<form action='/snippet/create' method='POST'>",
 		},
@@ -328,7 +401,7 @@ func TestCreateSnippet(t *testing.T) {
 				form.Add("email", tt.userEmail)
 				form.Add("password", tt.userPassword)
 				form.Add("csrf_token", validCSRFToken)
-				_, _, _ = ts.postForm(t, tt.urlPath, form)
+				_, _, _ = ts.postForm(t, "/user/login", form)
 
 				code, _, body := ts.get(t, tt.urlPath) //here we access the route
 				assert.Equal(t, code, tt.wantCode)
@@ -342,3 +415,1870 @@ func TestCreateSnippet(t *testing.T) {
 		})
 	}
 }
+
+func TestUserProfile(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Valid ID",
+			urlPath:  "/user/profile/1",
+			wantCode: http.StatusOK,
+			wantBody: "Alice Jones",
+		},
+		{
+			name:     "Public snippets",
+			urlPath:  "/user/profile/1",
+			wantCode: http.StatusOK,
+			wantBody: "<a href='/s/aB3xK9mQ/an-old-silent-pond'>An old silent pond</a>",
+		},
+		{
+			name:     "Star counts",
+			urlPath:  "/user/profile/1",
+			wantCode: http.StatusOK,
+			wantBody: "<td>&#9733; 1</td>",
+		},
+		{
+			name:     "Page out of range",
+			urlPath:  "/user/profile/1?page=2",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Non-existent ID",
+			urlPath:  "/user/profile/2",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "String ID",
+			urlPath:  "/user/profile/foo",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+				// The profile page is public, so it must never leak the
+				// user's email address.
+				assert.Equal(t, strings.Contains(body, "alice@example.com"), false)
+			}
+		})
+	}
+}
+
+func TestEditSnippet(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Anonymous users are sent to the login page.
+	code, headers, _ := ts.get(t, "/snippet/edit/1")
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, headers.Get("Location"), "/user/login")
+
+	ts.login(t, "alice@example.com")
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Own snippet",
+			urlPath:  "/snippet/edit/1",
+			wantCode: http.StatusOK,
+			wantBody: "<form action='/snippet/edit/aB3xK9mQ' method='POST'>",
+		},
+		{
+			name:     "Several files",
+			urlPath:  "/snippet/edit/8",
+			wantCode: http.StatusOK,
+			wantBody: "<input type='text' name='files[0].name' value='compose.yaml'>",
+		},
+		{
+			name:     "Someone else's snippet",
+			urlPath:  "/snippet/edit/3",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Non-existent ID",
+			urlPath:  "/snippet/edit/2",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestEditSnippetPost(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t, "alice@example.com")
+
+	_, _, body := ts.get(t, "/snippet/edit/1")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		title        string
+		content      string
+		expires      string
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "Valid submission",
+			urlPath:      "/snippet/edit/1",
+			title:        "An old silent pond",
+			content:      "A frog jumps into the pond...",
+			expires:      "7",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/s/aB3xK9mQ/an-old-silent-pond",
+		},
+		{
+			name:     "Empty title",
+			urlPath:  "/snippet/edit/1",
+			title:    "",
+			content:  "A frog jumps into the pond...",
+			expires:  "7",
+			wantCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "Invalid expiry",
+			urlPath:  "/snippet/edit/1",
+			title:    "An old silent pond",
+			content:  "A frog jumps into the pond...",
+			expires:  "400",
+			wantCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "Someone else's snippet",
+			urlPath:  "/snippet/edit/3",
+			title:    "Mine now",
+			content:  "Mine now",
+			expires:  "7",
+			wantCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("title", tt.title)
+			form.Add("content", tt.content)
+			form.Add("expires", tt.expires)
+			form.Add("csrf_token", validCSRFToken)
+
+			code, headers, _ := ts.postForm(t, tt.urlPath, form)
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantLocation != "" {
+				assert.Equal(t, headers.Get("Location"), tt.wantLocation)
+			}
+		})
+	}
+}
+
+// expirySnippetModel wraps the mock snippet model and applies updates to a
+// single stored expiry, so tests can see what an edit did to it.
+type expirySnippetModel struct {
+	mocks.SnippetModel
+	expires time.Time
+}
+
+func (m *expirySnippetModel) Update(id int, in models.SnippetInput) error {
+	if err := m.SnippetModel.Update(id, in); err != nil {
+		return err
+	}
+	if !in.KeepExpiry {
+		m.expires = in.Expires
+	}
+	return nil
+}
+
+func TestEditSnippetKeepExpiry(t *testing.T) {
+	app := newTestApplication(t)
+	expires := time.Now().UTC().Add(2 * time.Hour).Truncate(time.Second)
+	snippets := &expirySnippetModel{expires: expires}
+	app.snippets = snippets
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t, "alice@example.com")
+
+	// The edit form offers to keep the current expiry, and picks it already.
+	_, _, body := ts.get(t, "/snippet/edit/1")
+	assert.StringContains(t, body, "<input type='radio' name='expires' value='keep' checked>")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	form := url.Values{}
+	form.Add("title", "An old silent pond")
+	form.Add("content", "A frog jumps into the pond...")
+	form.Add("expires", "keep")
+	form.Add("csrf_token", validCSRFToken)
+
+	code, _, _ := ts.postForm(t, "/snippet/edit/1", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, snippets.expires, expires)
+
+	// Choosing a new expiry still replaces it.
+	form.Set("expires", "7")
+	code, _, _ = ts.postForm(t, "/snippet/edit/1", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, snippets.expires.After(expires), true)
+}
+
+func TestDeleteSnippet(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t, "alice@example.com")
+
+	_, _, body := ts.get(t, "/s/aB3xK9mQ")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		csrfToken    string
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "Delete own snippet",
+			urlPath:      "/snippet/delete/1",
+			csrfToken:    validCSRFToken,
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/",
+		},
+		{
+			name:         "Expire own snippet",
+			urlPath:      "/snippet/expire/1",
+			csrfToken:    validCSRFToken,
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/",
+		},
+		{
+			name:      "Invalid CSRF Token",
+			urlPath:   "/snippet/delete/1",
+			csrfToken: "wrongToken",
+			wantCode:  http.StatusBadRequest,
+		},
+		{
+			name:      "Delete someone else's snippet",
+			urlPath:   "/snippet/delete/3",
+			csrfToken: validCSRFToken,
+			wantCode:  http.StatusForbidden,
+		},
+		{
+			name:      "Expire someone else's snippet",
+			urlPath:   "/snippet/expire/3",
+			csrfToken: validCSRFToken,
+			wantCode:  http.StatusForbidden,
+		},
+		{
+			name:      "Non-existent ID",
+			urlPath:   "/snippet/delete/2",
+			csrfToken: validCSRFToken,
+			wantCode:  http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("csrf_token", tt.csrfToken)
+
+			code, headers, _ := ts.postForm(t, tt.urlPath, form)
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantLocation != "" {
+				assert.Equal(t, headers.Get("Location"), tt.wantLocation)
+			}
+		})
+	}
+}
+
+func TestAdminRestore(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		wantCode int
+	}{
+		{
+			name:     "Admin",
+			email:    "admin@example.com",
+			wantCode: http.StatusSeeOther,
+		},
+		{
+			name:     "Regular user",
+			email:    "alice@example.com",
+			wantCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Each sub-test gets its own server so that logins don't leak
+			// between them.
+			app := newTestApplication(t)
+			ts := newTestServer(t, app.routes())
+			defer ts.Close()
+
+			ts.login(t, tt.email)
+
+			_, _, body := ts.get(t, "/")
+			form := url.Values{}
+			form.Add("csrf_token", extractCSRFToken(t, body))
+
+			code, _, _ := ts.get(t, "/admin/deleted")
+			if tt.wantCode == http.StatusForbidden {
+				assert.Equal(t, code, http.StatusForbidden)
+			} else {
+				assert.Equal(t, code, http.StatusOK)
+			}
+
+			code, _, _ = ts.postForm(t, "/admin/restore/2", form)
+			assert.Equal(t, code, tt.wantCode)
+		})
+	}
+}
+
+func TestSnippetHistory(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "History",
+			urlPath:  "/snippet/view/1/history",
+			wantCode: http.StatusOK,
+			wantBody: "An old pond",
+		},
+		{
+			name:     "History of non-existent snippet",
+			urlPath:  "/snippet/view/2/history",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Unified diff",
+			urlPath:  "/snippet/view/1/diff?from=1&to=0",
+			wantCode: http.StatusOK,
+			wantBody: "@@ -1,1 &#43;1,1 @@",
+		},
+		{
+			name:     "Side-by-side diff",
+			urlPath:  "/snippet/view/1/diff?from=1&to=0&view=split",
+			wantCode: http.StatusOK,
+			wantBody: "<table class='diff split'>",
+		},
+		{
+			name:     "Identical versions",
+			urlPath:  "/snippet/view/1/diff?from=0&to=0",
+			wantCode: http.StatusOK,
+			wantBody: "identical",
+		},
+		{
+			name:     "Other files",
+			urlPath:  "/snippet/view/8/diff?from=2&to=0",
+			wantCode: http.StatusOK,
+			wantBody: "<td><pre>-  app:</pre></td>",
+		},
+		{
+			name:     "Missing revision",
+			urlPath:  "/snippet/view/1/diff?from=7&to=0",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Revision of another snippet",
+			urlPath:  "/snippet/view/3/diff?from=1&to=0",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Bad revision",
+			urlPath:  "/snippet/view/1/diff?from=foo&to=0",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSnippetList(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "First page",
+			urlPath:  "/snippets",
+			wantCode: http.StatusOK,
+			wantBody: "An old silent pond",
+		},
+		{
+			name:     "Explicit page",
+			urlPath:  "/snippets?page=1",
+			wantCode: http.StatusOK,
+			wantBody: "<td>aB3xK9mQ</td>",
+		},
+		{
+			name:     "Past the last page",
+			urlPath:  "/snippets?page=2",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Zero page",
+			urlPath:  "/snippets?page=0",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "String page",
+			urlPath:  "/snippets?page=foo",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Huge page",
+			urlPath:  "/snippets?page=9223372036854775807",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Empty query",
+			urlPath:  "/search",
+			wantCode: http.StatusOK,
+			wantBody: "<form action='/search' method='GET' class='search'>",
+		},
+		{
+			name:     "Match",
+			urlPath:  "/search?q=pond",
+			wantCode: http.StatusOK,
+			wantBody: "An old silent <mark>pond</mark>",
+		},
+		{
+			name:     "Phrase",
+			urlPath:  "/search?q=%22silent+pond%22",
+			wantCode: http.StatusOK,
+			wantBody: "<mark>silent pond</mark>",
+		},
+		{
+			name:     "Excluded",
+			urlPath:  "/search?q=pond+-silent",
+			wantCode: http.StatusOK,
+			wantBody: "No snippets matched your search.",
+		},
+		{
+			name:     "No match",
+			urlPath:  "/search?q=frog",
+			wantCode: http.StatusOK,
+			wantBody: "No snippets matched your search.",
+		},
+		{
+			name:     "Bad page",
+			urlPath:  "/search?q=pond&page=foo",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestCreateSnippetPost(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t, "alice@example.com")
+
+	_, _, body := ts.get(t, "/snippet/create")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name         string
+		title        string
+		tags         string
+		language     string
+		format       string
+		visibility   string
+		password     string
+		filename     string
+		files        [][2]string // name and content of each extra file
+		wantCode     int
+		wantLocation string
+		wantBody     string
+	}{
+		{
+			name:         "Valid submission",
+			title:        "An old silent pond",
+			tags:         "haiku, Nature  c++",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/s/hG5tR2wX/an-old-silent-pond",
+		},
+		{
+			name:     "Empty title",
+			title:    "",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field cannot be blank",
+		},
+		{
+			name:     "Invalid tag",
+			title:    "An old silent pond",
+			tags:     "haiku <script>",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "Tags may only contain",
+		},
+		{
+			name:     "Too many tags",
+			title:    "An old silent pond",
+			tags:     "a b c d e f g h i j k",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "more than 10 tags",
+		},
+		{
+			name:         "Chosen language",
+			title:        "An old silent pond",
+			language:     "go",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/s/hG5tR2wX/an-old-silent-pond",
+		},
+		{
+			name:     "Unknown language",
+			title:    "An old silent pond",
+			language: "klingon",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field must be one of the listed languages",
+		},
+		{
+			name:         "Markdown",
+			title:        "Release notes",
+			format:       "markdown",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/s/hG5tR2wX/release-notes",
+		},
+		{
+			name:     "Unknown format",
+			title:    "Release notes",
+			format:   "html",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field must be plain text, code or Markdown",
+		},
+		{
+			name:         "Private",
+			title:        "An old silent pond",
+			visibility:   "private",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/s/hG5tR2wX/an-old-silent-pond",
+		},
+		{
+			name:       "Invalid visibility",
+			title:      "An old silent pond",
+			visibility: "secret",
+			wantCode:   http.StatusUnprocessableEntity,
+			wantBody:   "This field must be public, unlisted or private",
+		},
+		{
+			name:         "Password",
+			title:        "An old silent pond",
+			password:     "open sesame",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/s/hG5tR2wX/an-old-silent-pond",
+		},
+		{
+			name:     "Short password",
+			title:    "An old silent pond",
+			password: "sesame",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field must be at least 8 characters long",
+		},
+		{
+			name:         "Several files",
+			title:        "Snippetbox in Docker",
+			filename:     "Dockerfile",
+			files:        [][2]string{{"compose.yaml", "services:\n  web:\n    build: .\n"}},
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/s/hG5tR2wX/snippetbox-in-docker",
+		},
+		{
+			name:         "Blank file row",
+			title:        "An old silent pond",
+			files:        [][2]string{{"", ""}},
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/s/hG5tR2wX/an-old-silent-pond",
+		},
+		{
+			name:     "Unnamed main file",
+			title:    "Snippetbox in Docker",
+			files:    [][2]string{{"compose.yaml", "services:\n"}},
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "Name the main file when a snippet has more than one",
+		},
+		{
+			name:     "Duplicate file names",
+			title:    "Snippetbox in Docker",
+			filename: "Dockerfile",
+			files:    [][2]string{{"Dockerfile", "FROM scratch\n"}},
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "Every file must have a different name",
+		},
+		{
+			name:     "Blank file",
+			title:    "Snippetbox in Docker",
+			filename: "Dockerfile",
+			files:    [][2]string{{"compose.yaml", " "}},
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "Files cannot be blank",
+		},
+		{
+			name:     "Invalid file name",
+			title:    "An old silent pond",
+			filename: "../etc/passwd",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "File names may only contain letters, digits",
+		},
+		{
+			name:     "Too large",
+			title:    "Snippetbox in Docker",
+			filename: "Dockerfile",
+			files:    [][2]string{{"compose.yaml", strings.Repeat("a", defaultMaxSnippetBytes)}},
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "All the files together cannot be more than 512 KB",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("title", tt.title)
+			form.Add("content", "A frog jumps into the pond...")
+			form.Add("expires", "7")
+			form.Add("tags", tt.tags)
+			form.Add("language", tt.language)
+			form.Add("format", tt.format)
+			form.Add("visibility", tt.visibility)
+			form.Add("password", tt.password)
+			form.Add("filename", tt.filename)
+			for i, f := range tt.files {
+				form.Add(fmt.Sprintf("files[%d].name", i), f[0])
+				form.Add(fmt.Sprintf("files[%d].content", i), f[1])
+			}
+			form.Add("csrf_token", validCSRFToken)
+
+			code, headers, body := ts.postForm(t, "/snippet/create", form)
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantLocation != "" {
+				assert.Equal(t, headers.Get("Location"), tt.wantLocation)
+			}
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestCreateSnippetPostChunked(t *testing.T) {
+	app := newTestApplication(t)
+	app.maxSnippetBytes = 1024
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t, "alice@example.com")
+
+	_, _, body := ts.get(t, "/snippet/create")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name        string
+		tokenHeader bool
+	}{
+		// Browsers send the token in the form, after the content, so nosurf
+		// runs into the limit while looking for it.
+		{name: "Token in the form"},
+		// Other clients can send it in a header, and then the limit is only
+		// hit when the handler parses the form.
+		{name: "Token in a header", tokenHeader: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("title", "O snail")
+			form.Add("content", strings.Repeat("a", int(app.maxBodyBytes())))
+			form.Add("expires", "7")
+			if !tt.tokenHeader {
+				form.Add("csrf_token", validCSRFToken)
+			}
+
+			// Wrapping the body hides its length, so it's sent chunked and
+			// only cut off once too much of it has been read.
+			req, err := http.NewRequest(http.MethodPost, ts.URL+"/snippet/create", io.MultiReader(strings.NewReader(form.Encode())))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.tokenHeader {
+				req.Header.Set("X-CSRF-Token", validCSRFToken)
+			}
+
+			rs, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rs.Body.Close()
+
+			b, err := io.ReadAll(rs.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, rs.StatusCode, http.StatusRequestEntityTooLarge)
+			assert.StringContains(t, string(b), "Snippets can be up to 1 KB")
+		})
+	}
+
+	// A bad token in a form of the right size is still just a bad request.
+	form := url.Values{}
+	form.Add("title", "O snail")
+	form.Add("csrf_token", "wrong")
+
+	code, _, _ := ts.postForm(t, "/snippet/create", form)
+	assert.Equal(t, code, http.StatusBadRequest)
+}
+
+func TestTagList(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Used tag",
+			urlPath:  "/tag/haiku",
+			wantCode: http.StatusOK,
+			wantBody: "An old silent pond",
+		},
+		{
+			name:     "Unused tag",
+			urlPath:  "/tag/sql",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Invalid tag",
+			urlPath:  "/tag/Haiku!",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Tag cloud",
+			urlPath:  "/",
+			wantCode: http.StatusOK,
+			wantBody: "<a class='tag weight-5' href='/tag/haiku'",
+		},
+		{
+			name:     "Chips on snippet",
+			urlPath:  "/s/aB3xK9mQ",
+			wantCode: http.StatusOK,
+			wantBody: "<a class='tag' href='/tag/nature'>nature</a>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSnippetRaw(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name            string
+		urlPath         string
+		wantCode        int
+		wantBody        string
+		wantDisposition string
+	}{
+		{
+			name:     "Raw",
+			urlPath:  "/snippet/raw/1",
+			wantCode: http.StatusOK,
+			wantBody: "An old silent pond...",
+		},
+		{
+			name:            "Download",
+			urlPath:         "/snippet/download/1",
+			wantCode:        http.StatusOK,
+			wantBody:        "An old silent pond...",
+			wantDisposition: `attachment; filename="an-old-silent-pond.txt"`,
+		},
+		{
+			name:     "Markdown source",
+			urlPath:  "/snippet/raw/9",
+			wantCode: http.StatusOK,
+			wantBody: "# Release notes\n\n- **Faster** search\n- See [the docs](https://example.com/docs)\n\n<script>alert(1)</script>\n",
+		},
+		{
+			name:            "Markdown download",
+			urlPath:         "/snippet/download/9",
+			wantCode:        http.StatusOK,
+			wantBody:        "# Release notes\n\n- **Faster** search\n- See [the docs](https://example.com/docs)\n\n<script>alert(1)</script>\n",
+			wantDisposition: `attachment; filename="release-notes.md"`,
+		},
+		{
+			name:     "File",
+			urlPath:  "/snippet/raw/8/compose.yaml",
+			wantCode: http.StatusOK,
+			wantBody: "services:\n  web:\n    build: .\n",
+		},
+		{
+			name:     "Main file",
+			urlPath:  "/snippet/raw/8/Dockerfile",
+			wantCode: http.StatusOK,
+			wantBody: "FROM golang:1.21\nRUN go build ./cmd/web\n",
+		},
+		{
+			name:     "Non-existent file",
+			urlPath:  "/snippet/raw/8/main.go",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Unnamed main file",
+			urlPath:  "/snippet/raw/1/an-old-silent-pond.txt",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Non-existent ID",
+			urlPath:  "/snippet/raw/2",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "String ID",
+			urlPath:  "/snippet/download/foo",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, headers, body := ts.get(t, tt.urlPath)
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.Equal(t, headers.Get("Content-Type"), "text/plain; charset=utf-8")
+				assert.Equal(t, body, tt.wantBody)
+			}
+			if tt.wantDisposition != "" {
+				assert.Equal(t, headers.Get("Content-Disposition"), tt.wantDisposition)
+			}
+		})
+	}
+}
+
+func TestPrivateSnippet(t *testing.T) {
+	paths := []string{"/s/pL5vH8dZ", "/snippet/raw/4", "/snippet/view/4/history"}
+
+	tests := []struct {
+		name     string
+		email    string
+		wantCode int
+	}{
+		{
+			name:     "Anonymous",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Other user",
+			email:    "admin@example.com",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Author",
+			email:    "alice@example.com",
+			wantCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			ts := newTestServer(t, app.routes())
+			defer ts.Close()
+
+			if tt.email != "" {
+				ts.login(t, tt.email)
+			}
+
+			for _, path := range paths {
+				code, _, _ := ts.get(t, path)
+				assert.Equal(t, code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestUnlockSnippet(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, _, body := ts.get(t, "/s/sE4kJ6yN")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "This snippet is protected")
+	assert.Equal(t, strings.Contains(body, "web:pass"), false)
+
+	code, headers, _ := ts.get(t, "/snippet/raw/sE4kJ6yN")
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, headers.Get("Location"), "/s/sE4kJ6yN/database-settings")
+
+	validCSRFToken := extractCSRFToken(t, body)
+
+	unlock := func(password string) (int, http.Header, string) {
+		form := url.Values{}
+		form.Add("password", password)
+		form.Add("csrf_token", validCSRFToken)
+		return ts.postForm(t, "/snippet/unlock/sE4kJ6yN", form)
+	}
+
+	code, _, body = unlock("sesame")
+	assert.Equal(t, code, http.StatusUnprocessableEntity)
+	assert.StringContains(t, body, "The password is incorrect")
+
+	code, headers, _ = unlock("open sesame")
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, headers.Get("Location"), "/s/sE4kJ6yN/database-settings")
+
+	code, _, body = ts.get(t, "/s/sE4kJ6yN")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "web:pass")
+
+	code, _, body = ts.get(t, "/snippet/raw/sE4kJ6yN")
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, body, "dsn: web:pass@/snippetbox")
+}
+
+func TestUnlockSnippetRateLimit(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, body := ts.get(t, "/s/sE4kJ6yN")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	form := url.Values{}
+	form.Add("password", "wrong")
+	form.Add("csrf_token", validCSRFToken)
+
+	for i := 0; i < 5; i++ {
+		code, _, _ := ts.postForm(t, "/snippet/unlock/sE4kJ6yN", form)
+		assert.Equal(t, code, http.StatusUnprocessableEntity)
+	}
+
+	// Even the right password is refused once the limit has been hit.
+	form.Set("password", "open sesame")
+	code, _, body := ts.postForm(t, "/snippet/unlock/sE4kJ6yN", form)
+	assert.Equal(t, code, http.StatusTooManyRequests)
+	assert.StringContains(t, body, "Too many wrong passwords")
+}
+
+func TestBurnSnippet(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, _, body := ts.get(t, "/s/bU9rM3tW")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "correct horse battery staple")
+	assert.StringContains(t, body, "This snippet has now been deleted")
+
+	// The history would show the content without burning it.
+	code, _, _ = ts.get(t, "/snippet/view/bU9rM3tW/history")
+	assert.Equal(t, code, http.StatusNotFound)
+
+	code, _, body = ts.get(t, "/snippet/raw/bU9rM3tW")
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, body, "correct horse battery staple")
+
+	// Creating one shows the link rather than redirecting to it.
+	ts.login(t, "alice@example.com")
+
+	_, _, body = ts.get(t, "/snippet/create")
+
+	form := url.Values{}
+	form.Add("title", "Wifi password")
+	form.Add("content", "correct horse battery staple")
+	form.Add("expires", "burn")
+	form.Add("csrf_token", extractCSRFToken(t, body))
+
+	code, _, body = ts.postForm(t, "/snippet/create", form)
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "/s/hG5tR2wX/wifi-password")
+	assert.StringContains(t, body, "deleted the first time somebody reads it")
+}
+
+func TestForkSnippet(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, headers, _ := ts.get(t, "/snippet/fork/3")
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, headers.Get("Location"), "/user/login")
+
+	ts.login(t, "alice@example.com")
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		wantCode     int
+		wantBody     string
+		wantLocation string
+	}{
+		{
+			name:     "Other user's snippet",
+			urlPath:  "/snippet/fork/3",
+			wantCode: http.StatusOK,
+			wantBody: "<input type='hidden' name='parent' value='wF7nT2cR'>",
+		},
+		{
+			name:     "Own snippet",
+			urlPath:  "/snippet/fork/1",
+			wantCode: http.StatusOK,
+			wantBody: "An old silent pond...",
+		},
+		{
+			name:     "Burn after reading",
+			urlPath:  "/snippet/fork/bU9rM3tW",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:         "Locked",
+			urlPath:      "/snippet/fork/sE4kJ6yN",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/s/sE4kJ6yN/database-settings",
+		},
+		{
+			name:     "Non-existent ID",
+			urlPath:  "/snippet/fork/2",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, headers, body := ts.get(t, tt.urlPath)
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+			if tt.wantLocation != "" {
+				assert.Equal(t, headers.Get("Location"), tt.wantLocation)
+			}
+		})
+	}
+
+	t.Run("Save", func(t *testing.T) {
+		_, _, body := ts.get(t, "/snippet/fork/3")
+
+		form := url.Values{}
+		form.Add("title", "Over the wintry forest")
+		form.Add("content", "Over the wintry forest, winds howl in rage...")
+		form.Add("expires", "7")
+		form.Add("parent", "wF7nT2cR")
+		form.Add("csrf_token", extractCSRFToken(t, body))
+
+		code, headers, _ := ts.postForm(t, "/snippet/create", form)
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/s/hG5tR2wX/over-the-wintry-forest")
+	})
+
+	t.Run("Forks listed", func(t *testing.T) {
+		_, _, body := ts.get(t, "/s/aB3xK9mQ")
+		assert.StringContains(t, body, "1 fork")
+		assert.StringContains(t, body, "An old silent pond (remix)")
+
+		_, _, body = ts.get(t, "/s/fK2qX7hD")
+		assert.StringContains(t, body, "forked from <a href='/s/aB3xK9mQ/an-old-silent-pond'>An old silent pond</a>")
+	})
+}
+
+func TestComments(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, _, body := ts.get(t, "/s/aB3xK9mQ")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "2 comments")
+	assert.StringContains(t, body, "What a <strong>lovely</strong> &lt;haiku&gt;")
+	assert.StringContains(t, body, "<div class='comment depth-1' id='comment-2'>")
+	assert.StringContains(t, body, "to leave a comment")
+	assert.Equal(t, strings.Contains(body, "/comment/delete/"), false)
+
+	ts.login(t, "alice@example.com")
+
+	t.Run("Delete links", func(t *testing.T) {
+		// The snippet owner can delete anything on their snippet...
+		_, _, body := ts.get(t, "/s/aB3xK9mQ")
+		assert.StringContains(t, body, "<form action='/comment/delete/1' method='POST'>")
+		assert.StringContains(t, body, "<form action='/comment/delete/2' method='POST'>")
+
+		// ...but not comments on other people's snippets.
+		_, _, body = ts.get(t, "/s/wF7nT2cR")
+		assert.StringContains(t, body, "Winter is coming")
+		assert.Equal(t, strings.Contains(body, "/comment/delete/3"), false)
+	})
+
+	_, _, body = ts.get(t, "/s/aB3xK9mQ")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	postTests := []struct {
+		name         string
+		urlPath      string
+		body         string
+		parentID     string
+		wantCode     int
+		wantLocation string
+		wantBody     string
+	}{
+		{
+			name:         "Comment",
+			urlPath:      "/snippet/comment/1",
+			body:         "Lovely!",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/s/aB3xK9mQ/an-old-silent-pond#comment-4",
+		},
+		{
+			name:         "Reply",
+			urlPath:      "/snippet/comment/1",
+			body:         "Lovely!",
+			parentID:     "1",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/s/aB3xK9mQ/an-old-silent-pond#comment-4",
+		},
+		{
+			name:     "Blank",
+			urlPath:  "/snippet/comment/1",
+			body:     "  ",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field cannot be blank",
+		},
+		{
+			name:     "Too long",
+			urlPath:  "/snippet/comment/1",
+			body:     strings.Repeat("a", 2001),
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field cannot be more than 2000 characters long",
+		},
+		{
+			name:     "Reply to another snippet",
+			urlPath:  "/snippet/comment/1",
+			body:     "Lovely!",
+			parentID: "3",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "The comment you replied to has been deleted",
+		},
+		{
+			name:     "Burn after reading",
+			urlPath:  "/snippet/comment/bU9rM3tW",
+			body:     "Lovely!",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Non-existent snippet",
+			urlPath:  "/snippet/comment/2",
+			body:     "Lovely!",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range postTests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("body", tt.body)
+			form.Add("parent_id", tt.parentID)
+			form.Add("csrf_token", validCSRFToken)
+
+			code, headers, body := ts.postForm(t, tt.urlPath, form)
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantLocation != "" {
+				assert.Equal(t, headers.Get("Location"), tt.wantLocation)
+			}
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+
+	deleteTests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+	}{
+		{"Comment on own snippet", "/comment/delete/1", http.StatusSeeOther},
+		{"Own comment", "/comment/delete/2", http.StatusSeeOther},
+		{"Someone else's", "/comment/delete/3", http.StatusForbidden},
+		{"Non-existent ID", "/comment/delete/9", http.StatusNotFound},
+	}
+
+	for _, tt := range deleteTests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("csrf_token", validCSRFToken)
+
+			code, _, _ := ts.postForm(t, tt.urlPath, form)
+			assert.Equal(t, code, tt.wantCode)
+		})
+	}
+}
+
+func TestStars(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	t.Run("Home page", func(t *testing.T) {
+		code, _, body := ts.get(t, "/")
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "Latest Snippets")
+		assert.StringContains(t, body, "<td>&#9733; 0</td>")
+
+		code, _, body = ts.get(t, "/?sort=stars")
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "Most Starred This Week")
+		assert.StringContains(t, body, "Over the wintry forest")
+
+		code, _, _ = ts.get(t, "/?sort=oldest")
+		assert.Equal(t, code, http.StatusNotFound)
+	})
+
+	code, headers, _ := ts.get(t, "/account/stars")
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, headers.Get("Location"), "/user/login")
+
+	ts.login(t, "alice@example.com")
+
+	t.Run("My stars", func(t *testing.T) {
+		code, _, body := ts.get(t, "/account/stars")
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "Over the wintry forest")
+
+		code, _, _ = ts.get(t, "/account/stars?page=2")
+		assert.Equal(t, code, http.StatusNotFound)
+	})
+
+	t.Run("Star button", func(t *testing.T) {
+		_, _, body := ts.get(t, "/s/wF7nT2cR")
+		assert.StringContains(t, body, "<button>Unstar</button>")
+
+		_, _, body = ts.get(t, "/s/aB3xK9mQ")
+		assert.StringContains(t, body, "<button>Star</button>")
+	})
+
+	_, _, body := ts.get(t, "/s/aB3xK9mQ")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		csrfToken    string
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "Star",
+			urlPath:      "/snippet/star/1",
+			csrfToken:    validCSRFToken,
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/s/aB3xK9mQ/an-old-silent-pond",
+		},
+		{
+			name:         "Unstar",
+			urlPath:      "/snippet/star/3",
+			csrfToken:    validCSRFToken,
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/s/wF7nT2cR/over-the-wintry-forest",
+		},
+		{
+			name:      "Burn after reading",
+			urlPath:   "/snippet/star/bU9rM3tW",
+			csrfToken: validCSRFToken,
+			wantCode:  http.StatusNotFound,
+		},
+		{
+			name:      "Non-existent ID",
+			urlPath:   "/snippet/star/2",
+			csrfToken: validCSRFToken,
+			wantCode:  http.StatusNotFound,
+		},
+		{
+			name:      "Missing CSRF token",
+			urlPath:   "/snippet/star/1",
+			csrfToken: "",
+			wantCode:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("csrf_token", tt.csrfToken)
+
+			code, headers, _ := ts.postForm(t, tt.urlPath, form)
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantLocation != "" {
+				assert.Equal(t, headers.Get("Location"), tt.wantLocation)
+			}
+		})
+	}
+}
+
+func TestCollections(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	t.Run("Public collection", func(t *testing.T) {
+		code, _, body := ts.get(t, "/collection/1")
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "Favourite haiku")
+		assert.StringContains(t, body, "Short poems about nature")
+		assert.StringContains(t, body, "An old silent pond")
+
+		code, _, _ = ts.get(t, "/collection/2")
+		assert.Equal(t, code, http.StatusNotFound)
+
+		code, _, _ = ts.get(t, "/collection/99")
+		assert.Equal(t, code, http.StatusNotFound)
+	})
+
+	code, headers, _ := ts.get(t, "/account/collections")
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, headers.Get("Location"), "/user/login")
+
+	ts.login(t, "alice@example.com")
+
+	t.Run("My collections", func(t *testing.T) {
+		code, _, body := ts.get(t, "/account/collections")
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "<a href='/collection/1'>Favourite haiku</a>")
+	})
+
+	t.Run("Owner controls", func(t *testing.T) {
+		_, _, body := ts.get(t, "/collection/1")
+		assert.StringContains(t, body, "<a href='/account/collection/edit/1'>Edit</a>")
+		assert.StringContains(t, body, "<button name='direction' value='up'>")
+
+		_, _, body = ts.get(t, "/collection/3")
+		assert.StringContains(t, body, "Winter")
+		if strings.Contains(body, "/account/collection/edit/3") {
+			t.Errorf("want no edit link on another user's collection")
+		}
+	})
+
+	t.Run("Add to collection form", func(t *testing.T) {
+		_, _, body := ts.get(t, "/s/wF7nT2cR")
+		assert.StringContains(t, body, "<button formaction='/account/collection/add/1'>Favourite haiku</button>")
+	})
+
+	t.Run("Edit form", func(t *testing.T) {
+		code, _, body := ts.get(t, "/account/collection/edit/1")
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "value='Favourite haiku'")
+
+		code, _, _ = ts.get(t, "/account/collection/edit/3")
+		assert.Equal(t, code, http.StatusForbidden)
+	})
+
+	_, _, body := ts.get(t, "/account/collection/create")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		fields       map[string]string
+		wantCode     int
+		wantLocation string
+		wantBody     string
+	}{
+		{
+			name:         "Create",
+			urlPath:      "/account/collection/create",
+			fields:       map[string]string{"name": "Spring", "visibility": "unlisted"},
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/collection/4",
+		},
+		{
+			name:     "Create without a name",
+			urlPath:  "/account/collection/create",
+			fields:   map[string]string{"name": "", "visibility": "public"},
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field cannot be blank",
+		},
+		{
+			name:     "Invalid visibility",
+			urlPath:  "/account/collection/create",
+			fields:   map[string]string{"name": "Spring", "visibility": "secret"},
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field must be public, unlisted or private",
+		},
+		{
+			name:         "Edit",
+			urlPath:      "/account/collection/edit/1",
+			fields:       map[string]string{"name": "Best haiku", "visibility": "public"},
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/collection/1",
+		},
+		{
+			name:     "Edit another user's collection",
+			urlPath:  "/account/collection/edit/3",
+			fields:   map[string]string{"name": "Mine now", "visibility": "public"},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:         "Delete",
+			urlPath:      "/account/collection/delete/1",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/account/collections",
+		},
+		{
+			name:     "Delete another user's collection",
+			urlPath:  "/account/collection/delete/3",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:         "Add snippet",
+			urlPath:      "/account/collection/add/1",
+			fields:       map[string]string{"snippet": "wF7nT2cR"},
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/s/wF7nT2cR/over-the-wintry-forest",
+		},
+		{
+			name:     "Add burn after reading snippet",
+			urlPath:  "/account/collection/add/1",
+			fields:   map[string]string{"snippet": "bU9rM3tW"},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Add another user's unlisted snippet",
+			urlPath:  "/account/collection/add/1",
+			fields:   map[string]string{"snippet": "sE4kJ6yN"},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Add non-existent snippet",
+			urlPath:  "/account/collection/add/1",
+			fields:   map[string]string{"snippet": "hG5tR2wX"},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Add to another user's collection",
+			urlPath:  "/account/collection/add/3",
+			fields:   map[string]string{"snippet_id": "1"},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:         "Remove snippet",
+			urlPath:      "/account/collection/remove/1",
+			fields:       map[string]string{"snippet_id": "3"},
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/collection/1",
+		},
+		{
+			name:     "Remove snippet not in collection",
+			urlPath:  "/account/collection/remove/1",
+			fields:   map[string]string{"snippet_id": "5"},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:         "Move snippet up",
+			urlPath:      "/account/collection/move/1",
+			fields:       map[string]string{"snippet_id": "3", "direction": "up"},
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/collection/1",
+		},
+		{
+			name:     "Move in an unknown direction",
+			urlPath:  "/account/collection/move/1",
+			fields:   map[string]string{"snippet_id": "3", "direction": "left"},
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("csrf_token", validCSRFToken)
+			for k, v := range tt.fields {
+				form.Add(k, v)
+			}
+
+			code, headers, body := ts.postForm(t, tt.urlPath, form)
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantLocation != "" {
+				assert.Equal(t, headers.Get("Location"), tt.wantLocation)
+			}
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t, "alice@example.com")
+
+	t.Run("My templates", func(t *testing.T) {
+		code, _, body := ts.get(t, "/account/templates")
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "<a href='/snippet/create?template=1'>Incident notes</a> (site-wide)")
+		assert.StringContains(t, body, "<a href='/account/template/edit/2'>Edit</a>")
+		if strings.Contains(body, "/account/template/edit/1") {
+			t.Errorf("want no edit link on a site-wide template")
+		}
+	})
+
+	t.Run("Template picker", func(t *testing.T) {
+		_, _, body := ts.get(t, "/snippet/create")
+		assert.StringContains(t, body, "<option value='2'>SQL query</option>")
+		assert.StringContains(t, body, "<option value='1'>Incident notes (site-wide)</option>")
+	})
+
+	t.Run("Create from template", func(t *testing.T) {
+		code, _, body := ts.get(t, "/snippet/create?template=2")
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "<input type='text' name='title' value='Query'>")
+		assert.StringContains(t, body, "<textarea name='content'>SELECT id FROM snippets WHERE </textarea>")
+		assert.StringContains(t, body, "<input type='radio' name='expires' value='custom' checked>")
+		assert.StringContains(t, body, "<input type='number' name='expires_amount' min='1' value='12'>")
+
+		for _, path := range []string{"/snippet/create?template=3", "/snippet/create?template=99", "/snippet/create?template=foo"} {
+			code, _, _ := ts.get(t, path)
+			assert.Equal(t, code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("Save snippet as template", func(t *testing.T) {
+		_, _, body := ts.get(t, "/s/wF7nT2cR")
+		assert.StringContains(t, body, "<a href='/account/template/create?snippet=wF7nT2cR'>Save as template</a>")
+
+		code, _, body := ts.get(t, "/account/template/create?snippet=aB3xK9mQ")
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "<input type='text' name='name' value='An old silent pond'")
+		if strings.Contains(body, "site_wide") {
+			t.Errorf("want no site-wide option for regular users")
+		}
+
+		// Burn after reading, and password-protected without the password.
+		for _, path := range []string{"/account/template/create?snippet=bU9rM3tW", "/account/template/create?snippet=sE4kJ6yN"} {
+			code, _, _ := ts.get(t, path)
+			assert.Equal(t, code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("Edit form", func(t *testing.T) {
+		code, _, body := ts.get(t, "/account/template/edit/2")
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "value='SQL query'")
+
+		code, _, _ = ts.get(t, "/account/template/edit/1")
+		assert.Equal(t, code, http.StatusForbidden)
+
+		code, _, _ = ts.get(t, "/account/template/edit/3")
+		assert.Equal(t, code, http.StatusNotFound)
+	})
+
+	_, _, body := ts.get(t, "/account/template/create")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	valid := map[string]string{"name": "Runbook", "content": "1. ", "format": "plain", "expires": "7"}
+	with := func(k, v string) map[string]string {
+		fields := map[string]string{}
+		for k, v := range valid {
+			fields[k] = v
+		}
+		fields[k] = v
+		return fields
+	}
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		fields       map[string]string
+		wantCode     int
+		wantLocation string
+		wantBody     string
+	}{
+		{
+			name:         "Create",
+			urlPath:      "/account/template/create",
+			fields:       valid,
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/account/templates",
+		},
+		{
+			name:     "Empty name",
+			urlPath:  "/account/template/create",
+			fields:   with("name", ""),
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field cannot be blank",
+		},
+		{
+			name:     "Unknown format",
+			urlPath:  "/account/template/create",
+			fields:   with("format", "pdf"),
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field must be plain text, code or Markdown",
+		},
+		{
+			name:     "Fixed date",
+			urlPath:  "/account/template/create",
+			fields:   with("expires", "date"),
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "expire on a fixed date",
+		},
+		{
+			name:     "Too long",
+			urlPath:  "/account/template/create",
+			fields:   map[string]string{"name": "Runbook", "content": "1. ", "format": "plain", "expires": "custom", "expires_amount": "400", "expires_unit": "days"},
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field must be no more than 365 days from now",
+		},
+		{
+			name:     "Site-wide",
+			urlPath:  "/account/template/create",
+			fields:   with("site_wide", "true"),
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:         "Edit",
+			urlPath:      "/account/template/edit/2",
+			fields:       valid,
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/account/templates",
+		},
+		{
+			name:     "Edit site-wide template",
+			urlPath:  "/account/template/edit/1",
+			fields:   valid,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Edit another user's template",
+			urlPath:  "/account/template/edit/3",
+			fields:   valid,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:         "Delete",
+			urlPath:      "/account/template/delete/2",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/account/templates",
+		},
+		{
+			name:     "Delete site-wide template",
+			urlPath:  "/account/template/delete/1",
+			wantCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("csrf_token", validCSRFToken)
+			for k, v := range tt.fields {
+				form.Add(k, v)
+			}
+
+			code, headers, body := ts.postForm(t, tt.urlPath, form)
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantLocation != "" {
+				assert.Equal(t, headers.Get("Location"), tt.wantLocation)
+			}
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSiteWideTemplates(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t, "admin@example.com")
+
+	code, _, body := ts.get(t, "/account/template/create")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "<input type='checkbox' name='site_wide' value='true' >")
+
+	_, _, body = ts.get(t, "/account/templates")
+	assert.StringContains(t, body, "<a href='/account/template/edit/1'>Edit</a>")
+
+	code, _, _ = ts.get(t, "/account/template/edit/1")
+	assert.Equal(t, code, http.StatusOK)
+
+	form := url.Values{}
+	form.Add("csrf_token", extractCSRFToken(t, body))
+	form.Add("name", "Incident notes")
+	form.Add("content", "## Impact")
+	form.Add("format", "markdown")
+	form.Add("expires", "never")
+	form.Add("site_wide", "true")
+
+	code, headers, _ := ts.postForm(t, "/account/template/create", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, headers.Get("Location"), "/account/templates")
+
+	code, _, _ = ts.postForm(t, "/account/template/delete/1", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+}
+
+func TestSnippetViews(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	// Views are counted once per session, and not at all for snippets which
+	// burn after reading.
+	ts.get(t, "/s/aB3xK9mQ")
+	ts.get(t, "/s/aB3xK9mQ")
+	ts.get(t, "/s/wF7nT2cR")
+	ts.get(t, "/s/bU9rM3tW")
+	assert.Equal(t, app.views.views[models.ViewKey{SnippetID: 1, Day: today}], 1)
+	assert.Equal(t, app.views.views[models.ViewKey{SnippetID: 3, Day: today}], 1)
+	assert.Equal(t, app.views.views[models.ViewKey{SnippetID: 6, Day: today}], 0)
+
+	// Only the author sees the chart.
+	_, _, body := ts.get(t, "/s/aB3xK9mQ")
+	assert.Equal(t, strings.Contains(body, "<svg class='views'"), false)
+
+	ts.login(t, "alice@example.com")
+
+	_, _, body = ts.get(t, "/s/aB3xK9mQ")
+	assert.StringContains(t, body, "15 views in the last 30 days")
+	assert.StringContains(t, body, "<svg class='views'")
+	assert.StringContains(t, body, fmt.Sprintf("<title>%s: 12</title>", today.Format("2 Jan")))
+
+	// The author's own views don't count.
+	ts.get(t, "/s/dC8gV1zP")
+	assert.Equal(t, app.views.views[models.ViewKey{SnippetID: 8, Day: today}], 0)
+}
@@ -6,10 +6,16 @@ import (
 	"fmt"
 	"net/http"
 	"runtime/debug"
+	"strconv"
+	"strings"
 	"time" // New import
+	"unicode"
 
+	"github.com/Baytancha/snip56/internal/highlight"
+	"github.com/Baytancha/snip56/internal/models"
 	"github.com/go-playground/form/v4" // New import
-	"github.com/justinas/nosurf"       // New import
+	"github.com/julienschmidt/httprouter"
+	"github.com/justinas/nosurf" // New import
 )
 
 // The serverError helper writes an error message and stack trace to the errorLog,
@@ -86,19 +92,30 @@ func (app *application) newTemplateData(r *http.Request) *templateData {
 		CurrentYear: time.Now().Year(),
 		Flash:       app.sessionManager.PopString(r.Context(), "flash"),
 		// Add the authentication status to the template data.
-		IsAuthenticated: app.isAuthenticated(r),
-		CSRFToken:       nosurf.Token(r),
+		IsAuthenticated:     app.isAuthenticated(r),
+		AuthenticatedUserID: app.authenticatedUserID(r),
+		CSRFToken:           nosurf.Token(r),
 	}
 }
 
-// Create a new decodePostForm() helper method. The second parameter here, dst,
-// is the target destination that we want to decode the form data into.
-func (app *application) decodePostForm(r *http.Request, dst any) error {
+// Create a new decodePostForm() helper method. The last parameter here, dst,
+// is the target destination that we want to decode the form data into. If the
+// form can't be read, the error response has already been sent when it
+// returns false, and the handler should just return.
+func (app *application) decodePostForm(w http.ResponseWriter, r *http.Request, dst any) bool {
 	// Call ParseForm() on the request, in the same way that we did in our
-	// createSnippetPost handler.
+	// createSnippetPost handler. A body cut off by limitRequestBody gets the
+	// "too large" page rather than a bare 400.
 	err := r.ParseForm()
 	if err != nil {
-		return err
+		var maxBytesError *http.MaxBytesError
+
+		if errors.As(err, &maxBytesError) {
+			app.requestTooLarge(w)
+		} else {
+			app.clientError(w, http.StatusBadRequest)
+		}
+		return false
 	}
 
 	// Call Decode() on our decoder instance, passing the target destination as
@@ -115,11 +132,12 @@ func (app *application) decodePostForm(r *http.Request, dst any) error {
 			panic(err)
 		}
 
-		// For all other errors, we return them as normal.
-		return err
+		// All other errors are the client's fault.
+		app.clientError(w, http.StatusBadRequest)
+		return false
 	}
 
-	return nil
+	return true
 }
 
 // Return true if the current request is from an authenticated user, otherwise
@@ -138,3 +156,394 @@ func (app *application) isAuthenticated(r *http.Request) bool {
 	// meaning we have to log in again
 	//return app.sessionManager.Exists(r.Context(), "authenticatedUserID")
 }
+
+// Return the ID of the current user if the request is authenticated, otherwise
+// return 0 (which never matches a real user ID).
+func (app *application) authenticatedUserID(r *http.Request) int {
+	if !app.isAuthenticated(r) {
+		return 0
+	}
+
+	return app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+}
+
+// The paramSnippet helper reads the :id parameter and fetches the snippet, as
+// seen by the current user. If anything is wrong it sends the appropriate
+// response itself and returns false, so handlers can simply return.
+func (app *application) paramSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	return app.lookupSnippet(w, r, params.ByName("id"))
+}
+
+// lookupSnippet fetches a snippet by its public ID or, for old URLs, its
+// integer key. Sequential keys are easy to guess, so only public snippets and
+// the viewer's own can be found by them; unlisted snippets stay hidden unless
+// you know their public ID.
+func (app *application) lookupSnippet(w http.ResponseWriter, r *http.Request, key string) (*models.Snippet, bool) {
+	viewerID := app.authenticatedUserID(r)
+
+	var snippet *models.Snippet
+	var err error
+
+	if id, atoiErr := strconv.Atoi(key); atoiErr == nil {
+		if id < 1 {
+			app.notFound(w)
+			return nil, false
+		}
+
+		snippet, err = app.snippets.Get(id, viewerID)
+		if err == nil && snippet.Visibility != models.VisibilityPublic && snippet.UserID != viewerID {
+			err = models.ErrNoRecord
+		}
+	} else {
+		if !models.PublicIDRX.MatchString(key) {
+			app.notFound(w)
+			return nil, false
+		}
+
+		snippet, err = app.snippets.GetByPublicID(key, viewerID)
+	}
+
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return nil, false
+	}
+
+	return snippet, true
+}
+
+// unlockKey is the session key recording that the current session has entered
+// the password for a snippet.
+func unlockKey(id int) string {
+	return fmt.Sprintf("unlockedSnippet:%d", id)
+}
+
+// unlocked reports whether the current user may see the content of a snippet:
+// either it has no password, or they wrote it, or they have already entered its
+// password in this session.
+func (app *application) unlocked(r *http.Request, s *models.Snippet) bool {
+	if !s.Protected() || s.UserID == app.authenticatedUserID(r) {
+		return true
+	}
+
+	return app.sessionManager.GetBool(r.Context(), unlockKey(s.ID))
+}
+
+// The unlockedSnippet helper works like paramSnippet, but sends the user to the
+// snippet's page to enter its password if they haven't already.
+func (app *application) unlockedSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	snippet, ok := app.paramSnippet(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	if !app.unlocked(r, snippet) {
+		http.Redirect(w, r, snippetURL(snippet), http.StatusSeeOther)
+		return nil, false
+	}
+
+	return snippet, true
+}
+
+// The burn helper is called when a snippet's content is about to be sent to
+// the user. Burn-after-reading snippets are deleted there and then, and the
+// copy read in the same transaction is returned; other snippets are returned
+// as they are. If someone else got to the snippet first it sends a 404 and
+// returns false.
+func (app *application) burn(w http.ResponseWriter, r *http.Request, s *models.Snippet) (*models.Snippet, bool) {
+	if !s.Burn {
+		return s, true
+	}
+
+	burned, err := app.snippets.Burn(s.ID, app.authenticatedUserID(r))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return nil, false
+	}
+
+	return burned, true
+}
+
+// renderSnippet renders the view page for a snippet the user is allowed to
+// see, together with the snippet it was forked from, its forks and comments, whether they've starred it, the
+// collections they could add it to and, for the author, its daily views. The
+// form is the new comment form, which is re-displayed with errors when a
+// comment isn't valid.
+func (app *application) renderSnippet(w http.ResponseWriter, r *http.Request, status int, s *models.Snippet, form commentForm) {
+	forks, err := app.snippets.Forks(s.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	// The parent is looked up as the viewer, so that it's only linked to while
+	// they can still see it.
+	var parent *models.Snippet
+	if s.ParentID != 0 {
+		parent, err = app.snippets.Get(s.ParentID, app.authenticatedUserID(r))
+		if err != nil && !errors.Is(err, models.ErrNoRecord) {
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	comments, err := app.comments.ForSnippet(s.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+
+	if data.IsAuthenticated {
+		data.Starred, err = app.snippets.Starred(s.ID, data.AuthenticatedUserID)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+
+		data.Collections, err = app.collections.ForUser(data.AuthenticatedUserID)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	// Only the author gets to see how often their snippet is viewed.
+	if s.UserID == data.AuthenticatedUserID && !s.Burn {
+		today := time.Now()
+
+		views, err := app.snippets.DailyViews(s.ID, today.AddDate(0, 0, 1-chartDays))
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+
+		data.ViewChart = newViewChart(views, today, chartDays)
+	}
+
+	data.Snippet = s
+	data.Parent = parent
+	data.Snippets = forks
+	data.Comments = comments
+	data.Form = form
+
+	app.render(w, status, "view.tmpl", data)
+}
+
+// expiresChoice returns the expiry option closest to a snippet's, for
+// pre-filling a template saved from it. Snippets that burn after reading or
+// never expire keep doing so; otherwise the default of a year is offered.
+func expiresChoice(s *models.Snippet) string {
+	switch {
+	case s.Burn:
+		return expiresBurn
+	case s.NeverExpires():
+		return expiresNever
+	default:
+		return "365"
+	}
+}
+
+// The ownedSnippet helper works like paramSnippet, but also checks that the
+// snippet belongs to the current user.
+func (app *application) ownedSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	snippet, ok := app.paramSnippet(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	// Only the author may change a snippet.
+	if snippet.UserID != app.authenticatedUserID(r) {
+		app.clientError(w, http.StatusForbidden)
+		return nil, false
+	}
+
+	return snippet, true
+}
+
+// The paramCollection helper fetches the collection whose ID is in the URL,
+// as seen by the current user. Like paramSnippet, it sends a 404 response and
+// returns false if there's no such collection.
+func (app *application) paramCollection(w http.ResponseWriter, r *http.Request) (*models.Collection, bool) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return nil, false
+	}
+
+	collection, err := app.collections.Get(id, app.authenticatedUserID(r))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return nil, false
+	}
+
+	return collection, true
+}
+
+// The ownedCollection helper works like paramCollection, but also checks that
+// the collection belongs to the current user.
+func (app *application) ownedCollection(w http.ResponseWriter, r *http.Request) (*models.Collection, bool) {
+	collection, ok := app.paramCollection(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	if collection.UserID != app.authenticatedUserID(r) {
+		app.clientError(w, http.StatusForbidden)
+		return nil, false
+	}
+
+	return collection, true
+}
+
+// The ownedTemplate helper fetches the template whose ID is in the URL and
+// checks that the current user may change it: their own templates, or
+// site-wide ones for admins. It sends a 404 response for templates the user
+// can't see at all.
+func (app *application) ownedTemplate(w http.ResponseWriter, r *http.Request) (*models.Template, bool) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return nil, false
+	}
+
+	userID := app.authenticatedUserID(r)
+
+	t, err := app.templates.Get(id, userID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return nil, false
+	}
+
+	allowed := t.UserID == userID
+	if t.SiteWide() {
+		allowed, err = app.isAdmin(r)
+		if err != nil {
+			app.serverError(w, err)
+			return nil, false
+		}
+	}
+
+	if !allowed {
+		app.clientError(w, http.StatusForbidden)
+		return nil, false
+	}
+
+	return t, true
+}
+
+// isAdmin reports whether the current user is an administrator.
+func (app *application) isAdmin(r *http.Request) (bool, error) {
+	id := app.authenticatedUserID(r)
+	if id == 0 {
+		return false, nil
+	}
+
+	user, err := app.users.GetbyID(id)
+	if err != nil {
+		return false, err
+	}
+
+	return user.Admin, nil
+}
+
+// slug squashes a snippet title down to lowercase letters, digits and
+// dashes, for use in URLs and file names. It may return "".
+func slug(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+		if b.Len() >= 50 {
+			break
+		}
+	}
+
+	return b.String()
+}
+
+// snippetURL returns the address of a snippet's page: its public ID followed
+// by a slug of its title, which is only there for people to read.
+func snippetURL(s *models.Snippet) string {
+	url := "/s/" + s.PublicID
+	if sl := slug(s.Title); sl != "" {
+		url += "/" + sl
+	}
+	return url
+}
+
+// downloadName returns the file name offered when a snippet is downloaded: its
+// title's slug followed by the extension for its language.
+func downloadName(s *models.Snippet) string {
+	name := slug(s.Title)
+	if name == "" {
+		name = "snippet-" + s.PublicID
+	}
+
+	return name + highlight.Ext(s.Language)
+}
+
+// splitTags turns the contents of a tags form field into a list of tags. Tags
+// can be separated by commas or whitespace; they are lowercased and duplicates
+// are dropped, keeping the order in which they were typed.
+func splitTags(s string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+
+	tags := []string{}
+	seen := map[string]bool{}
+
+	for _, f := range fields {
+		if !seen[f] {
+			seen[f] = true
+			tags = append(tags, f)
+		}
+	}
+
+	return tags
+}
+
+// diffText returns the text that versions of a snippet are compared on: the
+// main file, followed by each of the other files under a line naming it, so
+// that changes to any file show up in the diff.
+func diffText(r *models.Revision) string {
+	var b strings.Builder
+
+	b.WriteString(r.Content)
+	for _, f := range r.Files {
+		fmt.Fprintf(&b, "\n==> %s <==\n%s", f.Name, f.Content)
+	}
+
+	return b.String()
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"crypto/tls" // New import
 	"database/sql"
+	"errors"
 	"flag"
 
 	//"fmt"
@@ -10,6 +12,9 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 
 	//"runtime/debug"
 	"time"
@@ -33,20 +38,24 @@ import (
 // web application. For now we'll only include fields for the two custom loggers, but
 // we'll add more to it as the build progresses.
 type application struct {
-	debug    bool
-	errorLog *log.Logger
-	infoLog  *log.Logger
-	snippets models.SnippetModelInterface // Use our new interface type.
-	users    models.UserModelInterface    // Use our new interface type.
+	debug       bool
+	errorLog    *log.Logger
+	infoLog     *log.Logger
+	snippets    models.SnippetModelInterface // Use our new interface type.
+	users       models.UserModelInterface    // Use our new interface type.
+	comments    models.CommentModelInterface
+	collections models.CollectionModelInterface
+	templates   models.TemplateModelInterface // snippet templates, not HTML ones
 	//snippets       *models.SnippetModel
 	//users          *models.UserModel
 	templateCache  map[string]*template.Template
 	formDecoder    *form.Decoder
 	sessionManager *scs.SessionManager
-}
-
-func downloadHandler(w http.ResponseWriter, r *http.Request) {
-	http.ServeFile(w, r, "./ui/static/file.zip")
+	unlockLimiter  *failureLimiter // wrong passwords for protected snippets
+	views          *viewCounter
+	expiry         expiryLimits
+	// maxSnippetBytes is the largest snippet allowed, counting all its files.
+	maxSnippetBytes int
 }
 
 func openDB(dsn string) (*sql.DB, error) {
@@ -75,6 +84,23 @@ func main() {
 
 	addr := flag.String("addr", "127.0.0.1:4000", "HTTP network address")
 
+	// Site-wide limits on how long snippets can last.
+	minExpiry := flag.Duration("min-expiry", defaultExpiryLimits.Min, "Shortest time a snippet can be kept for")
+	maxExpiry := flag.Duration("max-expiry", defaultExpiryLimits.Max, "Longest time a snippet can be kept for")
+	allowNever := flag.Bool("allow-never-expire", defaultExpiryLimits.AllowNever, "Allow snippets that never expire")
+
+	// How often expired snippets are deleted from the database, and how many
+	// at a time.
+	reapInterval := flag.Duration("reap-interval", 10*time.Minute, "How often to purge expired snippets (0 to disable)")
+	reapBatch := flag.Int("reap-batch", 500, "Maximum number of expired snippets purged per statement")
+
+	// How big snippets can be, and when to compress them in the database.
+	maxSnippetSize := flag.Int("max-snippet-size", defaultMaxSnippetBytes, "Largest snippet allowed in bytes, counting all its files")
+	compressOver := flag.Int("compress-over", 0, "Store content over this many bytes gzipped, keeping the first 16 KB searchable (0 to disable)")
+
+	// How often buffered snippet views are written to the database.
+	viewsInterval := flag.Duration("views-interval", time.Minute, "How often to save snippet view counts")
+
 	// Importantly, we use the flag.Parse() function to parse the command-line flag.
 	// This reads in the command-line flag value and assigns it to the addr
 	// variable. You need to call this *before* you use the addr variable
@@ -82,6 +108,16 @@ func main() {
 	// encountered during parsing the application will be terminated.
 	flag.Parse()
 
+	if *viewsInterval <= 0 {
+		log.Fatal("views-interval must be positive")
+	}
+	if *maxSnippetSize <= 0 {
+		log.Fatal("max-snippet-size must be positive")
+	}
+	if *reapBatch <= 0 {
+		log.Fatal("reap-batch must be positive")
+	}
+
 	infoLog := log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime)
 
 	// Create a logger for writing error messages in the same way, but use stderr as
@@ -131,11 +167,22 @@ func main() {
 		debug:          *dbg,
 		errorLog:       errorLog, //not global vars but accessible via method interfsacing
 		infoLog:        infoLog,
-		snippets:       &models.SnippetModel{DB: db},
+		snippets:       &models.SnippetModel{DB: db, CompressOver: *compressOver},
 		users:          &models.UserModel{DB: db},
+		comments:       &models.CommentModel{DB: db},
+		collections:    &models.CollectionModel{DB: db},
+		templates:      &models.TemplateModel{DB: db},
 		templateCache:  templateCache,
 		formDecoder:    formDecoder,
 		sessionManager: sessionManager,
+		unlockLimiter:  newFailureLimiter(5, 15*time.Minute),
+		views:          newViewCounter(&models.SnippetModel{DB: db}, errorLog),
+		expiry: expiryLimits{
+			Min:        *minExpiry,
+			Max:        *maxExpiry,
+			AllowNever: *allowNever,
+		},
+		maxSnippetBytes: *maxSnippetSize,
 	}
 	// Initialize a tls.Config struct to hold the non-default TLS settings we
 	// want the server to use. In this case the only thing that we're changing
@@ -164,6 +211,61 @@ func main() {
 	//This means that if you’re using HTTPS (like we are) it’s sensible to set WriteTimeout
 	//to a value greater than ReadTimeout.
 
+	// Stop cleanly on Ctrl+C or SIGTERM: in-flight requests get a few seconds
+	// to finish, the reaper finishes the batch it's working on and buffered
+	// views are saved.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var wg sync.WaitGroup
+
+	if *reapInterval > 0 {
+		ticker := time.NewTicker(*reapInterval)
+		defer ticker.Stop()
+
+		r := &reaper{
+			snippets: &models.SnippetModel{DB: db},
+			batch:    *reapBatch,
+			now:      time.Now,
+			infoLog:  infoLog,
+			errorLog: errorLog,
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.run(ctx, ticker.C)
+		}()
+	}
+
+	viewTicker := time.NewTicker(*viewsInterval)
+	defer viewTicker.Stop()
+
+	// The view counter has its own context, cancelled only once the server
+	// has shut down, so that views counted by the requests still being served
+	// during the shutdown make it into the last flush.
+	viewsCtx, stopViews := context.WithCancel(context.Background())
+	defer stopViews()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		app.views.run(viewsCtx, viewTicker.C)
+	}()
+
+	// ListenAndServeTLS() returns as soon as Shutdown() is called, so wait for
+	// the shutdown itself to finish before exiting.
+	shutdownErr := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		infoLog.Print("Shutting down server")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		shutdownErr <- srv.Shutdown(ctx)
+	}()
+
 	infoLog.Printf("Starting server on %s", *addr)
 	// Call the ListenAndServe() method on our new http.Server struct.
 	err = srv.ListenAndServeTLS("C:\\Users\\mk\\snippetbox\\tls\\cert.pem", "C:\\Users\\mk\\snippetbox\\tls\\key.pem")
@@ -171,7 +273,27 @@ func main() {
 	//trace := fmt.Sprintf("%s\n%s", err.Error(), debug.Stack())
 	//app.errorLog.Println(trace)
 	//app.errorLog.Output(2, trace)
-	errorLog.Fatal(err)
+	// Errors are logged rather than fatal from here on, so that the background
+	// jobs still get to finish before exiting.
+	failed := false
+	if !errors.Is(err, http.ErrServerClosed) {
+		errorLog.Print(err)
+		failed = true
+		stop()
+	}
+
+	err = <-shutdownErr
+	if err != nil {
+		errorLog.Print(err)
+		failed = true
+	}
+
+	stopViews()
+	wg.Wait()
+	infoLog.Print("Stopped server")
+	if failed {
+		os.Exit(1)
+	}
 	//"C:\\Users\\mk\\snipptbox\\tls\\cert.pem", "C:\\Users\\mk\\snippetbox\\tls\\key.pem"
 
 	// Write messages using the two new loggers, instead of the standard logger.
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"errors"
 	"fmt" // New import
+	"io"
 	"net/http"
 
 	"context" // New import
@@ -75,20 +77,78 @@ func (app *application) requireAuthentication(next http.Handler) http.Handler {
 	})
 }
 
+// The requireAdmin middleware only lets administrators through. It must be used
+// after requireAuthentication, so that we know there is a logged-in user to
+// look up.
+func (app *application) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		admin, err := app.isAdmin(r)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+
+		if !admin {
+			app.clientError(w, http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// The limitRequestBody middleware stops request bodies bigger than
+// app.maxBodyBytes() from being read. Browsers say how big a form is up front,
+// so most oversized posts are turned away with the "too large" page before any
+// of the body is read; for the rest, MaxBytesReader makes reading fail part
+// way through.
+func (app *application) limitRequestBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		max := app.maxBodyBytes()
+
+		if r.ContentLength > max {
+			app.requestTooLarge(w)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, max)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 // Create a NoSurf middleware function which uses a customized CSRF cookie with
 // the Secure, Path and HttpOnly attributes set.
 // This will return a response which contains a CSRF cookie in the response headers and the CSRF token
 // for the signup page in the response body.
-func noSurf(next http.Handler) http.Handler {
+func (app *application) noSurf(next http.Handler) http.Handler {
 	csrfHandler := nosurf.New(next)
 	csrfHandler.SetBaseCookie(http.Cookie{
 		HttpOnly: true,
 		Path:     "/",
 		Secure:   true,
 	})
+	csrfHandler.SetFailureHandler(http.HandlerFunc(app.csrfFailure))
 
 	return csrfHandler
 }
+
+// csrfFailure is called by nosurf when a request's CSRF token is missing or
+// wrong. nosurf reads the form to find the token, so a body cut off by
+// limitRequestBody ends up here as a missing token, with the error swallowed.
+// Reading on from the body gives the error back, and the "too large" page is
+// shown instead of a bare 400.
+func (app *application) csrfFailure(w http.ResponseWriter, r *http.Request) {
+	_, err := io.Copy(io.Discard, r.Body)
+
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		app.requestTooLarge(w)
+		return
+	}
+
+	app.clientError(w, http.StatusBadRequest)
+}
 func (app *application) authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Retrieve the authenticatedUserID value from the session using the
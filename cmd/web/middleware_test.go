@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -73,3 +74,51 @@ func TestSecureHeaders(t *testing.T) {
 
 	assert.Equal(t, string(body), "OK")
 }
+
+func TestLimitRequestBody(t *testing.T) {
+	app := newTestApplication(t)
+	app.maxSnippetBytes = 1024
+
+	max := app.maxBodyBytes()
+
+	// next reads the whole body, as parsing a form would.
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := io.ReadAll(r.Body)
+		if err != nil {
+			var maxBytesError *http.MaxBytesError
+			assert.Equal(t, errors.As(err, &maxBytesError), true)
+			http.Error(w, "too big", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("OK"))
+	})
+
+	t.Run("Within the limit", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/snippet/create", bytes.NewReader(make([]byte, max)))
+
+		app.limitRequestBody(next).ServeHTTP(rr, r)
+
+		assert.Equal(t, rr.Code, http.StatusOK)
+	})
+
+	t.Run("Too large", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/snippet/create", bytes.NewReader(make([]byte, max+1)))
+
+		app.limitRequestBody(next).ServeHTTP(rr, r)
+
+		assert.Equal(t, rr.Code, http.StatusRequestEntityTooLarge)
+		assert.StringContains(t, rr.Body.String(), "Snippets can be up to 1 KB")
+	})
+
+	t.Run("Too large without a length", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/snippet/create", bytes.NewReader(make([]byte, max+1)))
+		r.ContentLength = -1
+
+		app.limitRequestBody(next).ServeHTTP(rr, r)
+
+		assert.Equal(t, rr.Code, http.StatusBadRequest)
+	})
+}
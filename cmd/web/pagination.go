@@ -0,0 +1,85 @@
+package main
+
+import (
+	"math"
+	"net/url"
+	"strconv"
+
+	"github.com/Baytancha/snip56/internal/models"
+)
+
+// A pagination holds what the "pagination" partial needs to render previous
+// and next links for a listing split over several pages.
+type pagination struct {
+	Page     int
+	PageSize int
+	Total    int
+	path     string
+	query    url.Values
+}
+
+// newPagination creates a pagination for the request URL u. The links it
+// builds keep any other query string parameters (such as a search term) and
+// only change "page".
+func newPagination(u *url.URL, page, pageSize, total int) *pagination {
+	return &pagination{
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+		path:     u.Path,
+		query:    u.Query(),
+	}
+}
+
+// LastPage returns the number of the last page, which is 1 even when there is
+// nothing to list.
+func (p *pagination) LastPage() int {
+	if p.Total == 0 {
+		return 1
+	}
+
+	return (p.Total + p.PageSize - 1) / p.PageSize
+}
+
+func (p *pagination) HasPrev() bool {
+	return p.Page > 1
+}
+
+func (p *pagination) HasNext() bool {
+	return p.Page < p.LastPage()
+}
+
+func (p *pagination) PrevURL() string {
+	return p.url(p.Page - 1)
+}
+
+func (p *pagination) NextURL() string {
+	return p.url(p.Page + 1)
+}
+
+func (p *pagination) url(page int) string {
+	q := url.Values{}
+	for k, v := range p.query {
+		q[k] = v
+	}
+	q.Set("page", strconv.Itoa(page))
+
+	return p.path + "?" + q.Encode()
+}
+
+// readPage reads the "page" query string parameter, defaulting to the first
+// page. It returns false if the value isn't a positive integer, or is so big
+// that the page's offset wouldn't fit in an int.
+func readPage(qs url.Values) (int, bool) {
+	s := qs.Get("page")
+	if s == "" {
+		return 1, true
+	}
+
+	page, err := strconv.Atoi(s)
+	if err != nil || page < 1 || page > math.MaxInt/models.SnippetPageSize {
+		return 0, false
+	}
+
+	return page, true
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"math"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/Baytancha/snip56/internal/assert"
+	"github.com/Baytancha/snip56/internal/models"
+)
+
+func TestPagination(t *testing.T) {
+	u, err := url.Parse("/search?q=pond&page=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		page     int
+		total    int
+		wantLast int
+		wantPrev bool
+		wantNext bool
+	}{
+		{
+			name:     "Empty",
+			page:     1,
+			total:    0,
+			wantLast: 1,
+		},
+		{
+			name:     "Single page",
+			page:     1,
+			total:    10,
+			wantLast: 1,
+		},
+		{
+			name:     "First of several",
+			page:     1,
+			total:    21,
+			wantLast: 3,
+			wantNext: true,
+		},
+		{
+			name:     "Middle",
+			page:     2,
+			total:    21,
+			wantLast: 3,
+			wantPrev: true,
+			wantNext: true,
+		},
+		{
+			name:     "Last",
+			page:     3,
+			total:    21,
+			wantLast: 3,
+			wantPrev: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPagination(u, tt.page, 10, tt.total)
+
+			assert.Equal(t, p.LastPage(), tt.wantLast)
+			assert.Equal(t, p.HasPrev(), tt.wantPrev)
+			assert.Equal(t, p.HasNext(), tt.wantNext)
+		})
+	}
+
+	// Links keep the other query string parameters.
+	p := newPagination(u, 2, 10, 21)
+	assert.Equal(t, p.NextURL(), "/search?page=3&q=pond")
+	assert.Equal(t, p.PrevURL(), "/search?page=1&q=pond")
+}
+
+func TestReadPage(t *testing.T) {
+	tests := []struct {
+		name     string
+		page     string
+		wantPage int
+		wantOK   bool
+	}{
+		{name: "Missing", page: "", wantPage: 1, wantOK: true},
+		{name: "Valid", page: "3", wantPage: 3, wantOK: true},
+		{name: "Zero", page: "0"},
+		{name: "Negative", page: "-1"},
+		{name: "Not a number", page: "foo"},
+		{name: "Offset overflows", page: strconv.Itoa(math.MaxInt/models.SnippetPageSize + 1)},
+		{name: "Too big for an int", page: "99999999999999999999"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qs := url.Values{}
+			if tt.page != "" {
+				qs.Set("page", tt.page)
+			}
+
+			page, ok := readPage(qs)
+			assert.Equal(t, ok, tt.wantOK)
+			if tt.wantOK {
+				assert.Equal(t, page, tt.wantPage)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// A failureLimiter counts failed attempts at something (such as unlocking a
+// snippet) per key, and refuses further attempts once there have been too many
+// failures within a sliding window. An attempt counts as failed from the moment
+// it is reserved until it is cleared by a success, so attempts made in parallel
+// can't get past the limit while each is still being checked. It is kept in
+// memory, so the count starts again when the server restarts.
+type failureLimiter struct {
+	mu       sync.Mutex
+	max      int
+	window   time.Duration
+	failures map[int][]time.Time
+	now      func() time.Time
+}
+
+func newFailureLimiter(max int, window time.Duration) *failureLimiter {
+	return &failureLimiter{
+		max:      max,
+		window:   window,
+		failures: map[int][]time.Time{},
+		now:      time.Now,
+	}
+}
+
+// Reserve records an attempt for the key and reports whether it may go ahead.
+// Refused attempts are not recorded.
+func (l *failureLimiter) Reserve(key int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	times := l.recent(key)
+	if len(times) >= l.max {
+		return false
+	}
+	l.failures[key] = append(times, l.now())
+
+	return true
+}
+
+// Clear forgets the attempts for the key, after one of them has succeeded.
+func (l *failureLimiter) Clear(key int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.failures, key)
+}
+
+// recent drops the failures for the key that have dropped out of the window
+// and returns the rest. The caller must hold the lock.
+func (l *failureLimiter) recent(key int) []time.Time {
+	cutoff := l.now().Add(-l.window)
+
+	times := l.failures[key]
+	i := 0
+	for i < len(times) && !times[i].After(cutoff) {
+		i++
+	}
+	times = times[i:]
+
+	if len(times) == 0 {
+		delete(l.failures, key)
+	} else {
+		l.failures[key] = times
+	}
+
+	return times
+}
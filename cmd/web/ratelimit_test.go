@@ -0,0 +1,59 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Baytancha/snip56/internal/assert"
+)
+
+func TestFailureLimiter(t *testing.T) {
+	now := time.Date(2024, 3, 17, 10, 15, 0, 0, time.UTC)
+
+	l := newFailureLimiter(3, time.Minute)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, l.Reserve(1), true)
+		now = now.Add(10 * time.Second)
+	}
+
+	// The fourth attempt is refused, but other keys are unaffected.
+	assert.Equal(t, l.Reserve(1), false)
+	assert.Equal(t, l.Reserve(2), true)
+
+	// Once the first attempt is more than a minute old, one more is allowed.
+	now = now.Add(31 * time.Second)
+	assert.Equal(t, l.Reserve(1), true)
+	assert.Equal(t, l.Reserve(1), false)
+
+	// Everything is forgotten after a quiet minute.
+	now = now.Add(time.Minute)
+	assert.Equal(t, l.Reserve(1), true)
+	assert.Equal(t, len(l.failures[1]), 1)
+
+	// A success clears the count straight away.
+	l.Clear(1)
+	assert.Equal(t, len(l.failures[1]), 0)
+}
+
+func TestFailureLimiterParallel(t *testing.T) {
+	l := newFailureLimiter(5, time.Minute)
+
+	var wg sync.WaitGroup
+	var allowed atomic.Int32
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if l.Reserve(1) {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, allowed.Load(), int32(5))
+}
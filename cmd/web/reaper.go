@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// snippetPurger is the part of the snippet model used by the reaper.
+type snippetPurger interface {
+	PurgeExpired(before time.Time, limit int) ([]int, error)
+}
+
+// A reaper periodically deletes expired snippets for good. Get() and friends
+// already hide expired snippets, so this only stops the snippets table from
+// growing forever.
+type reaper struct {
+	snippets snippetPurger
+	batch    int              // snippets deleted per statement
+	now      func() time.Time // the clock, replaceable in tests
+	infoLog  *log.Logger
+	errorLog *log.Logger
+}
+
+// run reaps once straight away and then every time ticks delivers, until the
+// context is cancelled.
+func (r *reaper) run(ctx context.Context, ticks <-chan time.Time) {
+	for {
+		r.reap(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticks:
+		}
+	}
+}
+
+// reap deletes every snippet that has expired by now, a batch at a time so
+// that no single statement holds locks for long. It stops early if the context
+// is cancelled, and returns the number of snippets deleted.
+func (r *reaper) reap(ctx context.Context) int {
+	now := r.now()
+	total := 0
+
+	for ctx.Err() == nil {
+		ids, err := r.snippets.PurgeExpired(now, r.batch)
+		if err != nil {
+			r.errorLog.Printf("reaper: %s", err)
+			break
+		}
+
+		total += len(ids)
+		if len(ids) > 0 {
+			r.infoLog.Printf("reaper: purged %d expired snippets: %v", len(ids), ids)
+		}
+
+		if len(ids) < r.batch {
+			break
+		}
+	}
+
+	return total
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Baytancha/snip56/internal/assert"
+)
+
+// fakePurger holds snippet expiry times by ID, and purges them the way the
+// real model does.
+type fakePurger struct {
+	mu      sync.Mutex
+	expires map[int]time.Time
+	calls   int
+	err     error
+}
+
+func (p *fakePurger) PurgeExpired(before time.Time, limit int) ([]int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	ids := []int{}
+	for id, t := range p.expires {
+		if !t.After(before) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	for _, id := range ids {
+		delete(p.expires, id)
+	}
+
+	return ids, nil
+}
+
+func (p *fakePurger) remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.expires)
+}
+
+func newTestReaper(p *fakePurger, now *time.Time) *reaper {
+	return &reaper{
+		snippets: p,
+		batch:    2,
+		now:      func() time.Time { return *now },
+		infoLog:  log.New(io.Discard, "", 0),
+		errorLog: log.New(io.Discard, "", 0),
+	}
+}
+
+func TestReap(t *testing.T) {
+	now := time.Date(2024, 3, 17, 10, 15, 0, 0, time.UTC)
+
+	p := &fakePurger{expires: map[int]time.Time{
+		1: now.Add(-time.Hour),
+		2: now.Add(-time.Minute),
+		3: now,
+		4: now.Add(time.Minute),
+		5: now.Add(time.Hour),
+	}}
+	r := newTestReaper(p, &now)
+
+	// Three snippets have expired, which takes two batches.
+	assert.Equal(t, r.reap(context.Background()), 3)
+	assert.Equal(t, p.calls, 2)
+	assert.Equal(t, p.remaining(), 2)
+
+	now = now.Add(2 * time.Hour)
+	assert.Equal(t, r.reap(context.Background()), 2)
+	assert.Equal(t, p.remaining(), 0)
+
+	// Errors are logged, not retried.
+	p.err = errors.New("connection refused")
+	p.calls = 0
+	assert.Equal(t, r.reap(context.Background()), 0)
+	assert.Equal(t, p.calls, 1)
+}
+
+func TestReaperRun(t *testing.T) {
+	now := time.Date(2024, 3, 17, 10, 15, 0, 0, time.UTC)
+
+	p := &fakePurger{expires: map[int]time.Time{
+		1: now.Add(-time.Hour),
+		2: now.Add(time.Hour),
+	}}
+	r := newTestReaper(p, &now)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ticks := make(chan time.Time)
+	done := make(chan struct{})
+
+	go func() {
+		r.run(ctx, ticks)
+		close(done)
+	}()
+
+	// The first pass happens straight away; the send only goes through once
+	// it's finished and run() is waiting for the next tick.
+	ticks <- now
+	assert.Equal(t, p.remaining(), 1)
+
+	now = now.Add(2 * time.Hour)
+	ticks <- now
+	ticks <- now // wait for the second pass to finish
+	assert.Equal(t, p.remaining(), 0)
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("reaper didn't stop after the context was cancelled")
+	}
+}
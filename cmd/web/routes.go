@@ -37,20 +37,63 @@ func (app *application) routes() http.Handler {
 	// And then create the routes using the appropriate methods, patterns and
 	// handlers.
 
-	router.Handler(http.MethodGet, "/", app.sessionManager.LoadAndSave(noSurf(app.authenticate(app.loginRedirect(http.HandlerFunc(app.home))))))
-	router.Handler(http.MethodGet, "/about", app.sessionManager.LoadAndSave(noSurf(app.authenticate(app.loginRedirect(http.HandlerFunc(app.about))))))
-	router.Handler(http.MethodGet, "/snippet/view/:id", app.sessionManager.LoadAndSave(noSurf(app.authenticate(app.loginRedirect(http.HandlerFunc(app.showSnippet))))))
-	router.Handler(http.MethodGet, "/user/signup", app.sessionManager.LoadAndSave(noSurf(app.authenticate(app.loginRedirect(http.HandlerFunc(app.userSignup))))))
-	router.Handler(http.MethodPost, "/user/signup", app.sessionManager.LoadAndSave(noSurf(app.authenticate(app.loginRedirect(http.HandlerFunc(app.userSignupPost))))))
-	router.Handler(http.MethodGet, "/user/login", app.sessionManager.LoadAndSave(noSurf(app.authenticate(http.HandlerFunc(app.userLogin)))))
-	router.Handler(http.MethodPost, "/user/login", app.sessionManager.LoadAndSave(noSurf(app.authenticate(http.HandlerFunc(app.userLoginPost)))))
+	router.Handler(http.MethodGet, "/", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(http.HandlerFunc(app.home))))))
+	router.Handler(http.MethodGet, "/snippets", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(http.HandlerFunc(app.snippetList))))))
+	router.Handler(http.MethodGet, "/tag/:name", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(http.HandlerFunc(app.tagList))))))
+	router.Handler(http.MethodGet, "/search", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(http.HandlerFunc(app.search))))))
+	router.Handler(http.MethodGet, "/about", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(http.HandlerFunc(app.about))))))
+	router.Handler(http.MethodGet, "/s/:pid", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(http.HandlerFunc(app.showSnippet))))))
+	router.Handler(http.MethodGet, "/s/:pid/:slug", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(http.HandlerFunc(app.showSnippet))))))
+	router.Handler(http.MethodGet, "/snippet/view/:id", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(http.HandlerFunc(app.snippetViewRedirect)))))
+	// Raw content is meant for scripts as much as browsers, so there is no
+	// point redirecting to a login page from here.
+	router.Handler(http.MethodGet, "/snippet/raw/:id", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(http.HandlerFunc(app.snippetRaw)))))
+	router.Handler(http.MethodGet, "/snippet/raw/:id/:name", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(http.HandlerFunc(app.snippetFileRaw)))))
+	router.Handler(http.MethodGet, "/snippet/download/:id", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(http.HandlerFunc(app.snippetDownload)))))
+	router.Handler(http.MethodGet, "/snippet/view/:id/history", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(http.HandlerFunc(app.snippetHistory))))))
+	router.Handler(http.MethodGet, "/snippet/view/:id/diff", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(http.HandlerFunc(app.snippetDiff))))))
+	router.Handler(http.MethodGet, "/user/profile/:id", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(http.HandlerFunc(app.userProfile))))))
+	router.Handler(http.MethodGet, "/collection/:id", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(http.HandlerFunc(app.collectionView))))))
+	router.Handler(http.MethodGet, "/user/signup", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(http.HandlerFunc(app.userSignup))))))
+	router.Handler(http.MethodPost, "/user/signup", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(http.HandlerFunc(app.userSignupPost))))))
+	router.Handler(http.MethodGet, "/user/login", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(http.HandlerFunc(app.userLogin)))))
+	router.Handler(http.MethodPost, "/user/login", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(http.HandlerFunc(app.userLoginPost)))))
 
 	//protected := dynamic.Append(app.requireAuthentication)
 	//router.Handler(http.MethodGet, "/snippet/create", protected.ThenFunc(app.snippetCreate))
-	router.Handler(http.MethodGet, "/account/view", app.sessionManager.LoadAndSave(noSurf(app.authenticate(app.loginRedirect(app.requireAuthentication(http.HandlerFunc(app.accountView)))))))
-	router.Handler(http.MethodGet, "/snippet/create", app.sessionManager.LoadAndSave(noSurf(app.authenticate(app.loginRedirect(app.requireAuthentication(http.HandlerFunc(app.createSnippet)))))))
-	router.Handler(http.MethodPost, "/snippet/create", app.sessionManager.LoadAndSave(noSurf(app.authenticate(app.loginRedirect(app.requireAuthentication(http.HandlerFunc(app.createSnippetPost)))))))
-	router.Handler(http.MethodPost, "/user/logout", app.sessionManager.LoadAndSave(noSurf(app.authenticate(app.loginRedirect(app.requireAuthentication(http.HandlerFunc(app.userLogoutPost)))))))
+	router.Handler(http.MethodGet, "/account/view", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(app.requireAuthentication(http.HandlerFunc(app.accountView)))))))
+	router.Handler(http.MethodGet, "/account/stars", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(app.requireAuthentication(http.HandlerFunc(app.accountStars)))))))
+	router.Handler(http.MethodGet, "/account/collections", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(app.requireAuthentication(http.HandlerFunc(app.accountCollections)))))))
+	router.Handler(http.MethodGet, "/account/collection/create", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(app.requireAuthentication(http.HandlerFunc(app.createCollection)))))))
+	router.Handler(http.MethodPost, "/account/collection/create", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(app.requireAuthentication(http.HandlerFunc(app.createCollectionPost)))))))
+	router.Handler(http.MethodGet, "/account/collection/edit/:id", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(app.requireAuthentication(http.HandlerFunc(app.editCollection)))))))
+	router.Handler(http.MethodGet, "/account/templates", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(app.requireAuthentication(http.HandlerFunc(app.accountTemplates)))))))
+	router.Handler(http.MethodGet, "/account/template/create", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(app.requireAuthentication(http.HandlerFunc(app.createTemplate)))))))
+	router.Handler(http.MethodPost, "/account/template/create", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(app.requireAuthentication(http.HandlerFunc(app.createTemplatePost)))))))
+	router.Handler(http.MethodGet, "/account/template/edit/:id", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(app.requireAuthentication(http.HandlerFunc(app.editTemplate)))))))
+	router.Handler(http.MethodGet, "/snippet/create", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(app.requireAuthentication(http.HandlerFunc(app.createSnippet)))))))
+	router.Handler(http.MethodPost, "/snippet/create", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(app.requireAuthentication(http.HandlerFunc(app.createSnippetPost)))))))
+	router.Handler(http.MethodGet, "/snippet/fork/:id", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(app.requireAuthentication(http.HandlerFunc(app.forkSnippet)))))))
+	router.Handler(http.MethodGet, "/snippet/edit/:id", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(app.requireAuthentication(http.HandlerFunc(app.editSnippet)))))))
+	// The POST-only routes below skip loginRedirect, as there would be nothing
+	// to GET at their URL after logging in.
+	router.Handler(http.MethodPost, "/snippet/edit/:id", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.requireAuthentication(http.HandlerFunc(app.editSnippetPost))))))
+	router.Handler(http.MethodPost, "/snippet/unlock/:id", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(http.HandlerFunc(app.unlockSnippetPost)))))
+	router.Handler(http.MethodPost, "/snippet/star/:id", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.requireAuthentication(http.HandlerFunc(app.starSnippetPost))))))
+	router.Handler(http.MethodPost, "/snippet/comment/:id", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.requireAuthentication(http.HandlerFunc(app.commentSnippetPost))))))
+	router.Handler(http.MethodPost, "/comment/delete/:id", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.requireAuthentication(http.HandlerFunc(app.deleteCommentPost))))))
+	router.Handler(http.MethodPost, "/account/collection/edit/:id", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.requireAuthentication(http.HandlerFunc(app.editCollectionPost))))))
+	router.Handler(http.MethodPost, "/account/collection/delete/:id", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.requireAuthentication(http.HandlerFunc(app.deleteCollectionPost))))))
+	router.Handler(http.MethodPost, "/account/collection/add/:id", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.requireAuthentication(http.HandlerFunc(app.collectionAddPost))))))
+	router.Handler(http.MethodPost, "/account/collection/remove/:id", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.requireAuthentication(http.HandlerFunc(app.collectionRemovePost))))))
+	router.Handler(http.MethodPost, "/account/collection/move/:id", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.requireAuthentication(http.HandlerFunc(app.collectionMovePost))))))
+	router.Handler(http.MethodPost, "/account/template/edit/:id", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.requireAuthentication(http.HandlerFunc(app.editTemplatePost))))))
+	router.Handler(http.MethodPost, "/account/template/delete/:id", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.requireAuthentication(http.HandlerFunc(app.deleteTemplatePost))))))
+	router.Handler(http.MethodPost, "/snippet/delete/:id", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.requireAuthentication(http.HandlerFunc(app.deleteSnippetPost))))))
+	router.Handler(http.MethodPost, "/snippet/expire/:id", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.requireAuthentication(http.HandlerFunc(app.expireSnippetPost))))))
+	router.Handler(http.MethodGet, "/admin/deleted", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(app.requireAuthentication(app.requireAdmin(http.HandlerFunc(app.adminDeleted))))))))
+	router.Handler(http.MethodPost, "/admin/restore/:id", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.requireAuthentication(app.requireAdmin(http.HandlerFunc(app.adminRestorePost)))))))
+	router.Handler(http.MethodPost, "/user/logout", app.sessionManager.LoadAndSave(app.noSurf(app.authenticate(app.loginRedirect(app.requireAuthentication(http.HandlerFunc(app.userLogoutPost)))))))
 
 	//мы попадем на хэндер только если у нас правильный метод
 	//router.HandlerFunc(http.MethodGet, "/", app.home)
@@ -79,6 +122,6 @@ func (app *application) routes() http.Handler {
 	//return app.logRequest(secureHeaders(mux))
 	//return secureHeaders(mux)
 
-	return app.recoverPanic(app.logRequest(secureHeaders(router)))
+	return app.recoverPanic(app.logRequest(secureHeaders(app.limitRequestBody(router))))
 
 }
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Baytancha/snip56/internal/validator"
+)
+
+// defaultMaxSnippetBytes matches the flag default in main().
+const defaultMaxSnippetBytes = 512 << 10
+
+// maxBodyBytes is the largest request body the server will read. Form encoding
+// can make content up to three times bigger, and there are other fields
+// besides, so any snippet within the size limit gets as far as validation,
+// where the user is told what's wrong with it.
+func (app *application) maxBodyBytes() int64 {
+	return 3*int64(app.maxSnippetBytes) + 64<<10
+}
+
+// requestTooLarge sends the "too large" page. It's used before the session
+// is loaded, so the page is rendered as if nobody were logged in.
+func (app *application) requestTooLarge(w http.ResponseWriter) {
+	data := &templateData{
+		CurrentYear:    time.Now().Year(),
+		MaxSnippetSize: app.maxSnippetBytes,
+	}
+
+	app.render(w, http.StatusRequestEntityTooLarge, "tooLarge.tmpl", data)
+}
+
+// checkSize checks the snippet against the size limit, which covers the main
+// file and any others together.
+func (form *snippetCreateForm) checkSize(max int) {
+	if !validator.MaxBytes(form.Content, max) {
+		form.AddFieldError("content", fmt.Sprintf("This field cannot be more than %s", formatSize(max)))
+		return
+	}
+
+	total := len(form.Content)
+	for _, f := range form.Files {
+		total += len(f.Content)
+	}
+
+	form.CheckField(total <= max, "files", fmt.Sprintf("All the files together cannot be more than %s", formatSize(max)))
+}
+
+// formatSize describes a number of bytes in whole megabytes or kilobytes
+// where it can, for use in messages.
+func formatSize(n int) string {
+	switch {
+	case n >= 1<<20 && n%(1<<20) == 0:
+		return fmt.Sprintf("%d MB", n>>20)
+	case n >= 1<<10 && n%(1<<10) == 0:
+		return fmt.Sprintf("%d KB", n>>10)
+	default:
+		return fmt.Sprintf("%d bytes", n)
+	}
+}
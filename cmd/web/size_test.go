@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Baytancha/snip56/internal/assert"
+)
+
+func TestCheckSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		form      snippetCreateForm
+		wantField string
+		wantErr   string
+	}{
+		{
+			name: "Within the limit",
+			form: snippetCreateForm{
+				Content: strings.Repeat("a", 512),
+				Files:   []snippetFileForm{{Name: "b.txt", Content: strings.Repeat("b", 512)}},
+			},
+		},
+		{
+			name:      "Content too large",
+			form:      snippetCreateForm{Content: strings.Repeat("a", 1025)},
+			wantField: "content",
+			wantErr:   "This field cannot be more than 1 KB",
+		},
+		{
+			// The limit is in bytes, not characters.
+			name:      "Multibyte characters",
+			form:      snippetCreateForm{Content: strings.Repeat("я", 513)},
+			wantField: "content",
+			wantErr:   "This field cannot be more than 1 KB",
+		},
+		{
+			name: "Files too large together",
+			form: snippetCreateForm{
+				Content: strings.Repeat("a", 512),
+				Files:   []snippetFileForm{{Name: "b.txt", Content: strings.Repeat("b", 513)}},
+			},
+			wantField: "files",
+			wantErr:   "All the files together cannot be more than 1 KB",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := tt.form
+			form.checkSize(1024)
+
+			if tt.wantErr == "" {
+				assert.Equal(t, form.Valid(), true)
+				return
+			}
+
+			assert.Equal(t, len(form.FieldErrors), 1)
+			assert.Equal(t, form.FieldErrors[tt.wantField], tt.wantErr)
+		})
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{n: 512 << 10, want: "512 KB"},
+		{n: 2 << 20, want: "2 MB"},
+		{n: 1536 << 10, want: "1536 KB"},
+		{n: 1000, want: "1000 bytes"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, formatSize(tt.n), tt.want)
+	}
+}
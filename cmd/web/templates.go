@@ -1,14 +1,24 @@
 package main
 
 import (
+	"bytes"
 	"fmt"           // New import
 	"html/template" // New import
 	"io/fs"         // New import
 	"path/filepath" // New import
+	"regexp"
+	"sort"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/Baytancha/snip56/internal/diff"
+	"github.com/Baytancha/snip56/internal/highlight"
 	"github.com/Baytancha/snip56/internal/models"
 	"github.com/Baytancha/snip56/ui"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
 )
 
 // Define a templateData type to act as the holding structure for
@@ -16,17 +26,79 @@ import (
 // At the moment it only contains one field, but we'll add more
 // to it as the build progresses.
 type templateData struct {
-	Snippet         *models.Snippet   //сниппет это связная совокупность данных таблицы
-	Snippets        []*models.Snippet //для того чтобы отображать последние n сниппетов
-	CurrentYear     int
-	Form            any
-	Flash           string
-	IsAuthenticated bool
-	CSRFToken       string
+	Snippet             *models.Snippet   //сниппет это связная совокупность данных таблицы
+	Snippets            []*models.Snippet //для того чтобы отображать последние n сниппетов
+	Parent              *models.Snippet   // the snippet Snippet was forked from, if it's still there
+	User                *models.User      // public profile being viewed
+	StarsReceived       int
+	StarsGiven          int
+	Collection          *models.Collection
+	Collections         []*models.Collection
+	Template            *models.Template
+	Templates           []*models.Template
+	Revisions           []*models.Revision
+	Comments            []*models.Comment
+	Diff                *diffView
+	Pagination          *pagination
+	Search              string   // the search box contents
+	SearchTerms         []string // words and phrases to highlight in results
+	Tag                 string   // tag being browsed
+	Sort                string   // home page ordering: "" for latest or "stars"
+	Starred             bool     // whether the current user starred Snippet
+	ViewChart           *viewChart
+	ShareURL            string // link to a new burn-after-reading snippet
+	MaxSnippetSize      int    // in bytes
+	TagCloud            []cloudTag
+	CurrentYear         int
+	Form                any
+	Flash               string
+	IsAuthenticated     bool
+	IsAdmin             bool // only set on pages with admin-only options
+	AuthenticatedUserID int  // 0 when nobody is logged in
+	CSRFToken           string
 	// Add an IsAuthenticated field to the templateData struct.
 	//We’ll use this Form field to pass the validation errors and previously submitted data back to the template when we re-display the form.
 } //Form holds user form data
 
+// A diffView holds a comparison between two versions of a snippet. Only one
+// of Hunks (unified view) and Rows (side-by-side view) is filled in, depending
+// on Split.
+type diffView struct {
+	From  *models.Revision
+	To    *models.Revision
+	Split bool
+	Hunks []diff.Hunk
+	Rows  []diff.Row
+}
+
+// A cloudTag is a tag as shown in the home page tag cloud. Weight runs from 1
+// to 5 and picks the font size, more popular tags being bigger.
+type cloudTag struct {
+	Name   string
+	Count  int
+	Weight int
+}
+
+// newTagCloud works out the weight of each tag relative to the most popular
+// one.
+func newTagCloud(tags []*models.Tag) []cloudTag {
+	most := 0
+	for _, t := range tags {
+		most = max(most, t.Count)
+	}
+
+	cloud := make([]cloudTag, 0, len(tags))
+	for _, t := range tags {
+		cloud = append(cloud, cloudTag{
+			Name:   t.Name,
+			Count:  t.Count,
+			Weight: 1 + 4*t.Count/most,
+		})
+	}
+
+	return cloud
+}
+
 // Create a humanDate function which returns a nicely formatted string
 // representation of a time.Time object.
 // чтобы функция работала в шаблоне она должна возвращать одно значение
@@ -41,13 +113,223 @@ func humanDate(t time.Time) string {
 	//return t.Format("02 Jan 2006 at 15:04")
 }
 
+// termsRX returns a case-insensitive regular expression matching any of the
+// given search terms, or nil if there are none. Spaces inside a phrase match
+// any run of whitespace, so phrases are found across line breaks too.
+func termsRX(terms []string) *regexp.Regexp {
+	if len(terms) == 0 {
+		return nil
+	}
+
+	// Try longer terms first so that "pond" doesn't win over "pond life".
+	sorted := append([]string(nil), terms...)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+
+	var alts []string
+	for _, t := range sorted {
+		var words []string
+		for _, w := range strings.Fields(t) {
+			words = append(words, regexp.QuoteMeta(w))
+		}
+		alts = append(alts, strings.Join(words, `\s+`))
+	}
+
+	return regexp.MustCompile(`(?i)(` + strings.Join(alts, "|") + `)`)
+}
+
+// markTerms HTML-escapes text and wraps every occurrence of the search terms in
+// a <mark> element.
+func markTerms(text string, terms []string) template.HTML {
+	rx := termsRX(terms)
+	if rx == nil {
+		return template.HTML(template.HTMLEscapeString(text))
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range rx.FindAllStringIndex(text, -1) {
+		b.WriteString(template.HTMLEscapeString(text[last:m[0]]))
+		b.WriteString("<mark>")
+		b.WriteString(template.HTMLEscapeString(text[m[0]:m[1]]))
+		b.WriteString("</mark>")
+		last = m[1]
+	}
+	b.WriteString(template.HTMLEscapeString(text[last:]))
+
+	return template.HTML(b.String())
+}
+
+// excerpt returns about n characters of text around the first occurrence of
+// any of the search terms, so that long snippets don't swamp the results page.
+func excerpt(text string, terms []string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+
+	start := 0
+	if rx := termsRX(terms); rx != nil {
+		if loc := rx.FindStringIndex(text); loc != nil {
+			start = max(utf8.RuneCountInString(text[:loc[0]])-n/4, 0)
+		}
+	}
+	end := min(start+n, len(runes))
+	start = max(end-n, 0)
+
+	s := string(runes[start:end])
+	if start > 0 {
+		s = "…" + s
+	}
+	if end < len(runes) {
+		s += "…"
+	}
+
+	return s
+}
+
+// Patterns for the inline markup allowed in comments, matched against text
+// which has already been HTML-escaped.
+var (
+	codeSpanRX = regexp.MustCompile("`([^`\n]+)`")
+	linkRX     = regexp.MustCompile(`\[([^\]\n]+)\]\((https?://[^\s)*]+)\)`)
+	boldRX     = regexp.MustCompile(`\*\*(\S(?:[^\n]*?\S)?)\*\*`)
+	italicRX   = regexp.MustCompile(`\*(\S(?:[^*\n]*?\S)?)\*`)
+)
+
+// commentHTML renders a comment written in a small subset of Markdown:
+// paragraphs, line breaks, `code`, fenced code blocks, **bold**, *italics* and
+// [links](https://...). Everything else is HTML-escaped, so users can't add
+// markup of their own.
+func commentHTML(body string) template.HTML {
+	var b strings.Builder
+	var para, code []string
+	inCode := false
+
+	flush := func() {
+		if len(para) > 0 {
+			b.WriteString("<p>" + strings.Join(para, "<br>\n") + "</p>\n")
+			para = nil
+		}
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			if inCode {
+				b.WriteString("<pre><code>" + template.HTMLEscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+				code = nil
+			} else {
+				flush()
+			}
+			inCode = !inCode
+			continue
+		}
+
+		switch {
+		case inCode:
+			code = append(code, line)
+		case strings.TrimSpace(line) == "":
+			flush()
+		default:
+			para = append(para, inlineHTML(line))
+		}
+	}
+
+	// An unclosed code block runs to the end of the comment.
+	if inCode {
+		b.WriteString("<pre><code>" + template.HTMLEscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+	}
+	flush()
+
+	return template.HTML(b.String())
+}
+
+// inlineHTML escapes a line of a comment and renders its inline markup. Code
+// spans are left exactly as typed.
+func inlineHTML(line string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range codeSpanRX.FindAllStringSubmatchIndex(line, -1) {
+		b.WriteString(emphasisHTML(line[last:m[0]]))
+		b.WriteString("<code>" + template.HTMLEscapeString(line[m[2]:m[3]]) + "</code>")
+		last = m[1]
+	}
+	b.WriteString(emphasisHTML(line[last:]))
+
+	return b.String()
+}
+
+func emphasisHTML(text string) string {
+	s := template.HTMLEscapeString(text)
+	s = linkRX.ReplaceAllString(s, `<a href="$2" rel="nofollow ugc">$1</a>`)
+	s = boldRX.ReplaceAllString(s, "<strong>$1</strong>")
+	s = italicRX.ReplaceAllString(s, "<em>$1</em>")
+	return s
+}
+
+// markdownRenderer turns GitHub-flavoured Markdown into HTML. Raw HTML in the
+// source is left out by goldmark, and markdownPolicy then strips anything
+// else we don't allow.
+var markdownRenderer = goldmark.New(
+	goldmark.WithExtensions(
+		extension.NewTable(extension.WithTableCellAlignMethod(extension.TableCellAlignAttribute)),
+		extension.Strikethrough,
+		extension.Linkify,
+	),
+)
+
+// markdownPolicy is the allowlist for rendered Markdown. It has no style
+// attributes, scripts or event handlers, and images must come from our own
+// site, so nothing it lets through is blocked by the Content-Security-Policy
+// set in secureHeaders.
+var markdownPolicy = func() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements("h1", "h2", "h3", "h4", "h5", "h6", "p", "br", "hr", "blockquote",
+		"pre", "code", "em", "strong", "del", "ul", "ol", "li",
+		"table", "thead", "tbody", "tr", "th", "td")
+	p.AllowAttrs("align").Matching(regexp.MustCompile(`^(left|center|right)$`)).OnElements("th", "td")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#.-]+$`)).OnElements("code")
+	p.AllowAttrs("start").Matching(bluemonday.Integer).OnElements("ol")
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowAttrs("title").OnElements("a", "img")
+	p.AllowAttrs("src").Matching(regexp.MustCompile(`^/[^/\\]`)).OnElements("img")
+	p.AllowAttrs("alt").OnElements("img")
+	p.AllowStandardURLs()
+	p.RequireNoFollowOnLinks(true)
+	return p
+}()
+
+// markdownHTML renders a Markdown snippet to sanitized HTML. The source is
+// still available, unchanged, from the raw and download views.
+func markdownHTML(content string) template.HTML {
+	var buf bytes.Buffer
+
+	err := markdownRenderer.Convert([]byte(content), &buf)
+	if err != nil {
+		// Converting from a string can't fail, but just in case show the
+		// source rather than nothing at all.
+		return template.HTML("<pre>" + template.HTMLEscapeString(content) + "</pre>")
+	}
+
+	return template.HTML(markdownPolicy.SanitizeBytes(buf.Bytes()))
+}
+
 // Initialize a template.FuncMap object and store it in a global variable. This is
 // essentially a string-keyed map which acts as a lookup between the names of our
 // custom template functions and the functions themselves.
 
 // чтобы зарегать функцию в таблице шаблонов нужно засунуть ее в карту
 var functions = template.FuncMap{
-	"humanDate": humanDate,
+	"humanDate":     humanDate,
+	"formatSize":    formatSize,
+	"highlight":     markTerms,
+	"excerpt":       excerpt,
+	"commentHTML":   commentHTML,
+	"markdown":      markdownHTML,
+	"snippetURL":    snippetURL,
+	"syntax":        highlight.HTML,
+	"syntaxPrefix":  highlight.HTMLPrefix,
+	"languageLabel": highlight.Label,
+	"languages":     func() []highlight.Language { return highlight.Languages },
 }
 
 func newTemplateCache() (map[string]*template.Template, error) {
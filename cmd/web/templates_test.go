@@ -1,6 +1,8 @@
 package main
 
 import (
+	"html/template"
+	"strings"
 	"testing"
 	"time"
 
@@ -78,3 +80,153 @@ func TestHumanDate(t *testing.T) {
 		})
 	}
 }
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		want  template.HTML
+	}{
+		{
+			name: "No terms",
+			text: "An <old> pond",
+			want: "An &lt;old&gt; pond",
+		},
+		{
+			name:  "Case-insensitive",
+			text:  "Old pond, old frog",
+			terms: []string{"old"},
+			want:  "<mark>Old</mark> pond, <mark>old</mark> frog",
+		},
+		{
+			name:  "Phrase across lines",
+			text:  "an old silent\npond",
+			terms: []string{"silent pond", "old"},
+			want:  "an <mark>old</mark> <mark>silent\npond</mark>",
+		},
+		{
+			name:  "Escapes matches",
+			text:  "a <b> c",
+			terms: []string{"<b>"},
+			want:  "a <mark>&lt;b&gt;</mark> c",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, markTerms(tt.text, tt.terms), tt.want)
+		})
+	}
+}
+
+func TestCommentHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want template.HTML
+	}{
+		{
+			name: "Paragraphs",
+			body: "An old pond\na frog\n\nsplash",
+			want: "<p>An old pond<br>\na frog</p>\n<p>splash</p>\n",
+		},
+		{
+			name: "Escapes HTML",
+			body: "<script>alert('hi')</script>",
+			want: "<p>&lt;script&gt;alert(&#39;hi&#39;)&lt;/script&gt;</p>\n",
+		},
+		{
+			name: "Emphasis",
+			body: "**very** *nice*",
+			want: "<p><strong>very</strong> <em>nice</em></p>\n",
+		},
+		{
+			name: "Code span",
+			body: "use `**x**` <here>",
+			want: "<p>use <code>**x**</code> &lt;here&gt;</p>\n",
+		},
+		{
+			name: "Code block",
+			body: "before\n```\nif a < b {\n```\nafter",
+			want: "<p>before</p>\n<pre><code>if a &lt; b {</code></pre>\n<p>after</p>\n",
+		},
+		{
+			name: "Link",
+			body: "[docs](https://go.dev/doc?a=1&b=2)",
+			want: "<p><a href=\"https://go.dev/doc?a=1&amp;b=2\" rel=\"nofollow ugc\">docs</a></p>\n",
+		},
+		{
+			name: "Unsafe link",
+			body: "[click](javascript:alert(1))",
+			want: "<p>[click](javascript:alert(1))</p>\n",
+		},
+		{
+			name: "Quote in link",
+			body: `[x](https://a.com/"onmouseover=alert(1))`,
+			want: "<p><a href=\"https://a.com/&#34;onmouseover=alert(1\" rel=\"nofollow ugc\">x</a>)</p>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, commentHTML(tt.body), tt.want)
+		})
+	}
+}
+
+func TestMarkdownHTML(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    template.HTML
+	}{
+		{
+			name:    "Headings and emphasis",
+			content: "# Notes\n\nSome **bold** and ~~old~~ text",
+			want:    "<h1>Notes</h1>\n<p>Some <strong>bold</strong> and <del>old</del> text</p>\n",
+		},
+		{
+			name:    "Raw HTML",
+			content: "<script>alert(1)</script>\n\nhi <b onclick='x()'>there</b>",
+			want:    "\n<p>hi there</p>\n",
+		},
+		{
+			name:    "Links",
+			content: "[click](javascript:alert(1)) and [docs](https://go.dev)",
+			want:    "<p>click and <a href=\"https://go.dev\" rel=\"nofollow\">docs</a></p>\n",
+		},
+		{
+			name:    "Code block",
+			content: "```go\nif a < b {}\n```",
+			want:    "<pre><code class=\"language-go\">if a &lt; b {}\n</code></pre>\n",
+		},
+		{
+			name:    "Images",
+			content: "![cat](https://example.com/cat.png) ![logo](/static/img/logo.png)",
+			want:    "<p><img alt=\"cat\"> <img src=\"/static/img/logo.png\" alt=\"logo\"></p>\n",
+		},
+		{
+			name:    "Table alignment",
+			content: "| a | b |\n|:--|--:|\n| 1 | 2 |",
+			want:    "<table>\n<thead>\n<tr>\n<th align=\"left\">a</th>\n<th align=\"right\">b</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td align=\"left\">1</td>\n<td align=\"right\">2</td>\n</tr>\n</tbody>\n</table>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, markdownHTML(tt.content), tt.want)
+		})
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	text := strings.Repeat("a ", 50) + "pond" + strings.Repeat(" b", 50)
+
+	got := excerpt(text, []string{"pond"}, 20)
+	assert.StringContains(t, got, "pond")
+	assert.Equal(t, strings.HasPrefix(got, "…"), true)
+	assert.Equal(t, strings.HasSuffix(got, "…"), true)
+
+	assert.Equal(t, excerpt("short", []string{"pond"}, 20), "short")
+}
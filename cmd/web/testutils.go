@@ -40,13 +40,20 @@ func newTestApplication(t *testing.T) *application {
 	sessionManager.Cookie.Secure = true
 
 	return &application{
-		errorLog:       log.New(io.Discard, "", 0),
-		infoLog:        log.New(io.Discard, "", 0),
-		snippets:       &mocks.SnippetModel{}, // Use the mock.
-		users:          &mocks.UserModel{},    // Use the mock.
-		templateCache:  templateCache,
-		formDecoder:    formDecoder,
-		sessionManager: sessionManager,
+		errorLog:        log.New(io.Discard, "", 0),
+		infoLog:         log.New(io.Discard, "", 0),
+		snippets:        &mocks.SnippetModel{}, // Use the mock.
+		users:           &mocks.UserModel{},    // Use the mock.
+		comments:        &mocks.CommentModel{},
+		collections:     &mocks.CollectionModel{},
+		templates:       &mocks.TemplateModel{},
+		templateCache:   templateCache,
+		formDecoder:     formDecoder,
+		sessionManager:  sessionManager,
+		unlockLimiter:   newFailureLimiter(5, 15*time.Minute),
+		views:           newViewCounter(&mocks.SnippetModel{}, log.New(io.Discard, "", 0)),
+		expiry:          defaultExpiryLimits,
+		maxSnippetBytes: defaultMaxSnippetBytes,
 	}
 
 	// return &application{
@@ -143,3 +150,20 @@ func (ts *testServer) postForm(t *testing.T, urlPath string, form url.Values) (i
 	// Return the response status, headers and body.
 	return rs.StatusCode, rs.Header, string(body)
 }
+
+// Create a login method which signs in as one of the mock users (all of them
+// share the password "pa$$word") using the test server client, so that later
+// requests made with the same client are authenticated.
+func (ts *testServer) login(t *testing.T, email string) {
+	_, _, body := ts.get(t, "/user/login")
+
+	form := url.Values{}
+	form.Add("email", email)
+	form.Add("password", "pa$$word")
+	form.Add("csrf_token", extractCSRFToken(t, body))
+
+	code, _, _ := ts.postForm(t, "/user/login", form)
+	if code != http.StatusSeeOther {
+		t.Fatalf("login failed with status %d", code)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Baytancha/snip56/internal/models"
+)
+
+// maxBufferedViews caps the number of snippet-days a viewCounter keeps while
+// the database can't be written to. Views beyond that are dropped.
+const maxBufferedViews = 100_000
+
+// viewStore is the part of the snippet model used by the view counter.
+type viewStore interface {
+	AddViews(views map[models.ViewKey]int) error
+}
+
+// A viewCounter buffers snippet views in memory and writes them to the
+// database every so often, so that viewing a snippet doesn't cost a write.
+type viewCounter struct {
+	mu       sync.Mutex
+	views    map[models.ViewKey]int
+	limit    int // the most snippet-days kept after a failed flush
+	store    viewStore
+	now      func() time.Time // the clock, replaceable in tests
+	errorLog *log.Logger
+}
+
+func newViewCounter(store viewStore, errorLog *log.Logger) *viewCounter {
+	return &viewCounter{
+		views:    map[models.ViewKey]int{},
+		limit:    maxBufferedViews,
+		store:    store,
+		now:      time.Now,
+		errorLog: errorLog,
+	}
+}
+
+// add records one view of a snippet today.
+func (c *viewCounter) add(snippetID int) {
+	key := models.ViewKey{
+		SnippetID: snippetID,
+		Day:       c.now().UTC().Truncate(24 * time.Hour),
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.views[key]++
+}
+
+// flush writes the buffered views to the store. If that fails they are kept
+// and tried again on the next flush, as long as the buffer stays within its
+// limit; the rest are dropped so that a long outage can't use up memory.
+func (c *viewCounter) flush() {
+	c.mu.Lock()
+	views := c.views
+	c.views = map[models.ViewKey]int{}
+	c.mu.Unlock()
+
+	if len(views) == 0 {
+		return
+	}
+
+	err := c.store.AddViews(views)
+	if err != nil {
+		c.errorLog.Printf("views: %s", err)
+
+		dropped := 0
+
+		c.mu.Lock()
+		for key, n := range views {
+			if _, ok := c.views[key]; !ok && len(c.views) >= c.limit {
+				dropped += n
+				continue
+			}
+			c.views[key] += n
+		}
+		c.mu.Unlock()
+
+		if dropped > 0 {
+			c.errorLog.Printf("views: dropped %d views", dropped)
+		}
+	}
+}
+
+// run flushes every time ticks delivers, and once more when the context is
+// cancelled so that no views are lost on shutdown.
+func (c *viewCounter) run(ctx context.Context, ticks <-chan time.Time) {
+	for {
+		select {
+		case <-ctx.Done():
+			c.flush()
+			return
+		case <-ticks:
+			c.flush()
+		}
+	}
+}
+
+// viewedKey is the session key holding the IDs of the snippets already
+// counted as viewed in the session, most recent last.
+const viewedKey = "viewedSnippets"
+
+// maxViewed is the number of snippet IDs remembered in each session.
+const maxViewed = 100
+
+// countView counts a view of the snippet, unless it's the author looking or
+// the snippet has already been viewed in this session.
+func (app *application) countView(r *http.Request, s *models.Snippet) {
+	if s.UserID == app.authenticatedUserID(r) {
+		return
+	}
+
+	ctx := r.Context()
+
+	viewed, _ := app.sessionManager.Get(ctx, viewedKey).([]int)
+	for _, id := range viewed {
+		if id == s.ID {
+			return
+		}
+	}
+
+	viewed = append(viewed, s.ID)
+	if len(viewed) > maxViewed {
+		viewed = viewed[len(viewed)-maxViewed:]
+	}
+	app.sessionManager.Put(ctx, viewedKey, viewed)
+
+	app.views.add(s.ID)
+}
+
+// chartDays is the number of days shown in a snippet's views chart.
+const chartDays = 30
+
+// A viewChart is a bar chart of a snippet's daily views, drawn as SVG by
+// view.tmpl. Coordinates are in SVG user units.
+type viewChart struct {
+	Width  int
+	Height int
+	Total  int
+	Max    int
+	Bars   []viewBar
+}
+
+// A viewBar is one day in a viewChart.
+type viewBar struct {
+	Day    time.Time
+	Views  int
+	X      int
+	Y      int
+	Width  int
+	Height int
+}
+
+// newViewChart lays out the views for the days days ending today, filling in
+// the days without any views.
+func newViewChart(views []*models.DailyViews, today time.Time, days int) *viewChart {
+	const barWidth, gap, height = 12, 2, 60
+
+	today = today.UTC().Truncate(24 * time.Hour)
+
+	counts := map[time.Time]int{}
+	for _, v := range views {
+		counts[v.Day.UTC().Truncate(24*time.Hour)] += v.Views
+	}
+
+	c := &viewChart{
+		Width:  days * (barWidth + gap),
+		Height: height,
+	}
+
+	for i := 0; i < days; i++ {
+		day := today.AddDate(0, 0, i-days+1)
+		c.Total += counts[day]
+		c.Max = max(c.Max, counts[day])
+		c.Bars = append(c.Bars, viewBar{Day: day, Views: counts[day]})
+	}
+
+	for i := range c.Bars {
+		b := &c.Bars[i]
+		b.X = i * (barWidth + gap)
+		b.Width = barWidth
+		if c.Max > 0 {
+			b.Height = b.Views * height / c.Max
+		}
+		b.Y = height - b.Height
+	}
+
+	return c
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Baytancha/snip56/internal/assert"
+	"github.com/Baytancha/snip56/internal/models"
+)
+
+// fakeViewStore adds up the views it's given, or fails with err.
+type fakeViewStore struct {
+	mu    sync.Mutex
+	views map[models.ViewKey]int
+	calls int
+	err   error
+}
+
+func (s *fakeViewStore) AddViews(views map[models.ViewKey]int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	if s.err != nil {
+		return s.err
+	}
+
+	for key, n := range views {
+		s.views[key] += n
+	}
+
+	return nil
+}
+
+func (s *fakeViewStore) get(key models.ViewKey) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.views[key]
+}
+
+func TestViewCounterFlush(t *testing.T) {
+	now := time.Date(2024, 3, 17, 23, 59, 0, 0, time.UTC)
+	today := models.ViewKey{SnippetID: 1, Day: time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC)}
+	tomorrow := models.ViewKey{SnippetID: 1, Day: time.Date(2024, 3, 18, 0, 0, 0, 0, time.UTC)}
+
+	store := &fakeViewStore{views: map[models.ViewKey]int{}}
+	c := newViewCounter(store, log.New(io.Discard, "", 0))
+	c.now = func() time.Time { return now }
+
+	// Nothing to write, so the store isn't bothered.
+	c.flush()
+	assert.Equal(t, store.calls, 0)
+
+	c.add(1)
+	c.add(1)
+	now = now.Add(2 * time.Minute)
+	c.add(1)
+
+	// A failed write keeps the views for next time.
+	store.err = errors.New("connection refused")
+	c.flush()
+	assert.Equal(t, store.get(today), 0)
+
+	store.err = nil
+	c.add(1)
+	c.flush()
+	assert.Equal(t, store.get(today), 2)
+	assert.Equal(t, store.get(tomorrow), 2)
+
+	// The buffer is empty again.
+	c.flush()
+	assert.Equal(t, store.calls, 2)
+}
+
+func TestViewCounterFlushLimit(t *testing.T) {
+	store := &fakeViewStore{views: map[models.ViewKey]int{}, err: errors.New("connection refused")}
+	c := newViewCounter(store, log.New(io.Discard, "", 0))
+	c.limit = 2
+
+	c.add(1)
+	c.add(2)
+	c.add(3)
+	c.flush()
+
+	// Only two snippets' views are kept while the store is down.
+	assert.Equal(t, len(c.views), 2)
+
+	// Once it's back they are written, and the buffer can fill up again.
+	store.err = nil
+	c.flush()
+	assert.Equal(t, len(store.views), 2)
+	assert.Equal(t, len(c.views), 0)
+}
+
+func TestViewCounterRun(t *testing.T) {
+	key := models.ViewKey{SnippetID: 3, Day: time.Now().UTC().Truncate(24 * time.Hour)}
+
+	store := &fakeViewStore{views: map[models.ViewKey]int{}}
+	c := newViewCounter(store, log.New(io.Discard, "", 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ticks := make(chan time.Time)
+	done := make(chan struct{})
+
+	go func() {
+		c.run(ctx, ticks)
+		close(done)
+	}()
+
+	c.add(3)
+	ticks <- time.Now()
+	ticks <- time.Now() // wait for the first flush to finish
+	assert.Equal(t, store.get(key), 1)
+
+	// Views still buffered when the server stops are saved.
+	c.add(3)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("view counter didn't stop after the context was cancelled")
+	}
+
+	assert.Equal(t, store.get(key), 2)
+}
+
+func TestNewViewChart(t *testing.T) {
+	today := time.Date(2024, 3, 17, 15, 0, 0, 0, time.UTC)
+
+	views := []*models.DailyViews{
+		{Day: time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC), Views: 2},
+		{Day: time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC), Views: 8},
+	}
+
+	c := newViewChart(views, today, 5)
+	assert.Equal(t, c.Total, 10)
+	assert.Equal(t, c.Max, 8)
+	assert.Equal(t, len(c.Bars), 5)
+
+	// Days without views are filled in, oldest first.
+	assert.Equal(t, c.Bars[0].Day, time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, c.Bars[0].Height, 0)
+	assert.Equal(t, c.Bars[0].Y, c.Height)
+
+	// The busiest day fills the chart and the others are scaled to it.
+	assert.Equal(t, c.Bars[4].Height, c.Height)
+	assert.Equal(t, c.Bars[4].Y, 0)
+	assert.Equal(t, c.Bars[1].Height, c.Height/4)
+	assert.Equal(t, c.Bars[4].X, 4*(c.Bars[0].Width+2))
+
+	// No views at all gives an empty chart rather than dividing by zero.
+	c = newViewChart(nil, today, 5)
+	assert.Equal(t, c.Total, 0)
+	assert.Equal(t, c.Bars[4].Height, 0)
+}
@@ -0,0 +1,260 @@
+// Package diff computes line-based differences between two texts, which we
+// use to compare snippet revisions.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op describes what happened to a line when going from the old text to the
+// new one.
+type Op int
+
+const (
+	Equal Op = iota
+	Delete
+	Insert
+)
+
+// String returns a short name for the operation. It doubles as the CSS class
+// used when rendering the line.
+func (o Op) String() string {
+	switch o {
+	case Delete:
+		return "del"
+	case Insert:
+		return "ins"
+	default:
+		return "eq"
+	}
+}
+
+// Marker returns the prefix used for the operation in a unified diff.
+func (o Op) Marker() string {
+	switch o {
+	case Delete:
+		return "-"
+	case Insert:
+		return "+"
+	default:
+		return " "
+	}
+}
+
+// Line is a single line of a diff. OldNum and NewNum are the 1-based line
+// numbers in the old and new texts, or 0 if the line isn't present there.
+type Line struct {
+	Op     Op
+	OldNum int
+	NewNum int
+	Text   string
+}
+
+// Hunk is a run of changed lines together with some unchanged lines around
+// them, as shown in a unified diff.
+type Hunk struct {
+	OldStart, OldLines int
+	NewStart, NewLines int
+	Lines              []Line
+}
+
+// Header returns the "@@ -1,3 +1,4 @@" line for the hunk.
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+}
+
+// Row is one row of a side-by-side diff. Either side may be nil when a line
+// only exists in one of the texts.
+type Row struct {
+	Left  *Line
+	Right *Line
+}
+
+// maxCells caps the size of the table used to find the longest common
+// subsequence. Beyond it we give up on finding a minimal diff and report the
+// differing middle section as entirely replaced.
+const maxCells = 4_000_000
+
+// Lines compares two texts line by line and returns every line of both, in
+// order, marked as kept, deleted or inserted.
+func Lines(a, b string) []Line {
+	x, y := split(a), split(b)
+
+	// Lines shared at the start and end don't need the expensive comparison,
+	// and in practice most edits only touch a small part of a snippet.
+	pre := 0
+	for pre < len(x) && pre < len(y) && x[pre] == y[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(x)-pre && suf < len(y)-pre && x[len(x)-1-suf] == y[len(y)-1-suf] {
+		suf++
+	}
+
+	var lines []Line
+	for i := 0; i < pre; i++ {
+		lines = append(lines, Line{Op: Equal, OldNum: i + 1, NewNum: i + 1, Text: x[i]})
+	}
+
+	lines = append(lines, middle(x[pre:len(x)-suf], y[pre:len(y)-suf], pre, pre)...)
+
+	for i := 0; i < suf; i++ {
+		oi, ni := len(x)-suf+i, len(y)-suf+i
+		lines = append(lines, Line{Op: Equal, OldNum: oi + 1, NewNum: ni + 1, Text: x[oi]})
+	}
+
+	return lines
+}
+
+// middle diffs the part of the texts that differs, using a longest common
+// subsequence table. oldOff and newOff are the number of lines before x and y
+// in the full texts, so that line numbers come out right.
+func middle(x, y []string, oldOff, newOff int) []Line {
+	var lines []Line
+
+	if len(x)*len(y) > maxCells {
+		for i, s := range x {
+			lines = append(lines, Line{Op: Delete, OldNum: oldOff + i + 1, Text: s})
+		}
+		for j, s := range y {
+			lines = append(lines, Line{Op: Insert, NewNum: newOff + j + 1, Text: s})
+		}
+		return lines
+	}
+
+	// lcs[i][j] holds the length of the longest common subsequence of x[i:]
+	// and y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			lines = append(lines, Line{Op: Equal, OldNum: oldOff + i + 1, NewNum: newOff + j + 1, Text: x[i]})
+			i++
+			j++
+		case j == len(y) || (i < len(x) && lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, Line{Op: Delete, OldNum: oldOff + i + 1, Text: x[i]})
+			i++
+		default:
+			lines = append(lines, Line{Op: Insert, NewNum: newOff + j + 1, Text: y[j]})
+			j++
+		}
+	}
+
+	return lines
+}
+
+// Hunks groups the changed lines of a diff into hunks, each surrounded by up to
+// context unchanged lines. Changes that are close enough for their context to
+// touch share a hunk. It returns nil if nothing changed.
+func Hunks(lines []Line, context int) []Hunk {
+	var changed []int
+	for i, l := range lines {
+		if l.Op != Equal {
+			changed = append(changed, i)
+		}
+	}
+
+	var hunks []Hunk
+
+	for k := 0; k < len(changed); {
+		first, last := changed[k], changed[k]
+		k++
+		for k < len(changed) && changed[k]-last-1 <= 2*context {
+			last = changed[k]
+			k++
+		}
+
+		start := max(first-context, 0)
+		end := min(last+context+1, len(lines))
+		hunks = append(hunks, newHunk(lines[start:end]))
+	}
+
+	return hunks
+}
+
+// newHunk works out the line ranges covered by a slice of diff lines.
+func newHunk(lines []Line) Hunk {
+	h := Hunk{Lines: lines}
+
+	for _, l := range lines {
+		if l.Op != Insert {
+			if h.OldStart == 0 {
+				h.OldStart = l.OldNum
+			}
+			h.OldLines++
+		}
+		if l.Op != Delete {
+			if h.NewStart == 0 {
+				h.NewStart = l.NewNum
+			}
+			h.NewLines++
+		}
+	}
+
+	return h
+}
+
+// SideBySide lays a diff out in two columns, old text on the left and new text
+// on the right. Deleted lines are paired up with the lines inserted in their
+// place so that replacements appear on the same row.
+func SideBySide(lines []Line) []Row {
+	var rows []Row
+
+	for i := 0; i < len(lines); {
+		if lines[i].Op == Equal {
+			rows = append(rows, Row{Left: &lines[i], Right: &lines[i]})
+			i++
+			continue
+		}
+
+		var dels, ins []*Line
+		for ; i < len(lines) && lines[i].Op == Delete; i++ {
+			dels = append(dels, &lines[i])
+		}
+		for ; i < len(lines) && lines[i].Op == Insert; i++ {
+			ins = append(ins, &lines[i])
+		}
+
+		for j := 0; j < max(len(dels), len(ins)); j++ {
+			var row Row
+			if j < len(dels) {
+				row.Left = dels[j]
+			}
+			if j < len(ins) {
+				row.Right = ins[j]
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	return rows
+}
+
+// split breaks a text into lines. Windows line endings are normalised, as
+// textareas submit them, and a single trailing newline doesn't count as an
+// extra empty line.
+func split(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.TrimSuffix(s, "\n")
+
+	return strings.Split(s, "\n")
+}
@@ -0,0 +1,102 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Baytancha/snip56/internal/assert"
+)
+
+// render writes a diff out in the usual unified style, one line per entry, so
+// that tests can compare it against a readable string.
+func render(lines []Line) string {
+	var b strings.Builder
+	for _, l := range lines {
+		b.WriteString(l.Op.Marker() + l.Text + "\n")
+	}
+	return b.String()
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want string
+	}{
+		{
+			name: "Identical",
+			a:    "one\ntwo\n",
+			b:    "one\ntwo\n",
+			want: " one\n two\n",
+		},
+		{
+			name: "Changed line",
+			a:    "one\ntwo\nthree",
+			b:    "one\n2\nthree",
+			want: " one\n-two\n+2\n three\n",
+		},
+		{
+			name: "Added lines",
+			a:    "one\nthree",
+			b:    "one\ntwo\nthree\nfour",
+			want: " one\n+two\n three\n+four\n",
+		},
+		{
+			name: "From empty",
+			a:    "",
+			b:    "one",
+			want: "+one\n",
+		},
+		{
+			name: "Windows line endings",
+			a:    "one\r\ntwo",
+			b:    "one\ntwo\n",
+			want: " one\n two\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, render(Lines(tt.a, tt.b)), tt.want)
+		})
+	}
+}
+
+func TestLineNumbers(t *testing.T) {
+	lines := Lines("a\nb\nc", "a\nx\nb\nc")
+
+	assert.Equal(t, len(lines), 4)
+	assert.Equal(t, lines[1].Op, Insert)
+	assert.Equal(t, lines[1].OldNum, 0)
+	assert.Equal(t, lines[1].NewNum, 2)
+	assert.Equal(t, lines[3].OldNum, 3)
+	assert.Equal(t, lines[3].NewNum, 4)
+}
+
+func TestHunks(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve"
+
+	hunks := Hunks(Lines(a, b), 2)
+
+	assert.Equal(t, len(hunks), 2)
+	assert.Equal(t, hunks[0].Header(), "@@ -1,5 +1,5 @@")
+	assert.Equal(t, hunks[1].Header(), "@@ -10,3 +10,3 @@")
+
+	// With more context the two changes are close enough to merge.
+	assert.Equal(t, len(Hunks(Lines(a, b), 4)), 1)
+
+	assert.Equal(t, len(Hunks(Lines(a, a), 2)), 0)
+}
+
+func TestSideBySide(t *testing.T) {
+	rows := SideBySide(Lines("a\nb\nc", "a\nB\nC\nD"))
+
+	assert.Equal(t, len(rows), 4)
+	assert.Equal(t, rows[0].Left.Text, "a")
+	assert.Equal(t, rows[1].Left.Text, "b")
+	assert.Equal(t, rows[1].Right.Text, "B")
+	assert.Equal(t, rows[3].Left == nil, true)
+	assert.Equal(t, rows[3].Right.Text, "D")
+}
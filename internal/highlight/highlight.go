@@ -0,0 +1,257 @@
+// Package highlight turns snippet content into syntax-highlighted HTML on the
+// server. The output only uses CSS classes (see ui/static/css/highlight.css),
+// never inline styles or scripts, so it works under our strict
+// Content-Security-Policy.
+package highlight
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// A Language is one of the languages users can pick for a snippet. Name is
+// what we store in the database and is also a chroma lexer name. Ext is the
+// file extension used when a snippet is downloaded.
+type Language struct {
+	Name  string
+	Label string
+	Ext   string
+}
+
+// Languages lists the languages offered in the snippet forms, in the order
+// they are shown.
+var Languages = []Language{
+	{"bash", "Bash", ".sh"},
+	{"c", "C", ".c"},
+	{"cpp", "C++", ".cpp"},
+	{"csharp", "C#", ".cs"},
+	{"css", "CSS", ".css"},
+	{"dockerfile", "Dockerfile", ".dockerfile"},
+	{"go", "Go", ".go"},
+	{"html", "HTML", ".html"},
+	{"java", "Java", ".java"},
+	{"javascript", "JavaScript", ".js"},
+	{"json", "JSON", ".json"},
+	{"makefile", "Makefile", ".mk"},
+	{"markdown", "Markdown", ".md"},
+	{"php", "PHP", ".php"},
+	{"python", "Python", ".py"},
+	{"ruby", "Ruby", ".rb"},
+	{"rust", "Rust", ".rs"},
+	{"sql", "SQL", ".sql"},
+	{"toml", "TOML", ".toml"},
+	{"typescript", "TypeScript", ".ts"},
+	{"yaml", "YAML", ".yaml"},
+	{"plaintext", "Plain text", ".txt"},
+}
+
+// Names returns the names of all the languages in Languages.
+func Names() []string {
+	names := make([]string, len(Languages))
+	for i, l := range Languages {
+		names[i] = l.Name
+	}
+	return names
+}
+
+// Label returns the human-readable name of a language, or "" if the language
+// isn't one we know about.
+func Label(name string) string {
+	for _, l := range Languages {
+		if l.Name == name {
+			return l.Label
+		}
+	}
+	return ""
+}
+
+// Ext returns the file extension for a language, including the leading dot.
+// Unknown languages are treated as plain text.
+func Ext(name string) string {
+	for _, l := range Languages {
+		if l.Name == name {
+			return l.Ext
+		}
+	}
+	return ".txt"
+}
+
+// A detector recognises a language from the content of a snippet.
+type detector struct {
+	language string
+	rx       *regexp.Regexp
+}
+
+// detectors are tried in order by Detect(), so more specific patterns come
+// before more general ones (C++ before C, TypeScript before JavaScript...).
+var detectors = []detector{
+	{"bash", regexp.MustCompile(`\A#!\S*\b(ba|z)?sh\b`)},
+	{"python", regexp.MustCompile(`\A#!\S*\bpython`)},
+	{"javascript", regexp.MustCompile(`\A#!\S*\bnode\b`)},
+	{"php", regexp.MustCompile(`\A\s*<\?php`)},
+	{"html", regexp.MustCompile(`(?i)\A\s*<(!doctype html|html|head|body|div|p|span|ul|table)\b`)},
+	{"go", regexp.MustCompile(`(?m)^package \w+\s*$`)},
+	{"dockerfile", regexp.MustCompile(`(?m)^FROM \S+(\s+AS \S+)?\s*$`)},
+	{"sql", regexp.MustCompile(`(?i)^\s*(SELECT\b[\s\S]*\bFROM|INSERT INTO|UPDATE \w+ SET|DELETE FROM|CREATE (TABLE|INDEX|VIEW)|ALTER TABLE|DROP TABLE)\b`)},
+	{"rust", regexp.MustCompile(`(?m)^\s*(pub )?fn \w+[<(]|\blet mut \w+|^use \w+::`)},
+	{"cpp", regexp.MustCompile(`(?m)^#include\s*<(iostream|vector|string|map)>|\bstd::\w+`)},
+	{"c", regexp.MustCompile(`(?m)^#include\s*[<"]\w+\.h[>"]`)},
+	{"csharp", regexp.MustCompile(`(?m)^using System(\.\w+)*;|\bnamespace \w+(\.\w+)*\s*\{?\s*$`)},
+	{"java", regexp.MustCompile(`(?m)^import java\.|\bpublic (static )?(final )?(class|void|interface) \w+`)},
+	{"python", regexp.MustCompile(`(?m)^\s*(def \w+\(.*\)( -> .+)?:\s*$|class \w+(\(.*\))?:\s*$|from [\w.]+ import \w+|import \w+(\.\w+)*\s*$)`)},
+	{"ruby", regexp.MustCompile(`(?m)^\s*(def \w+[?!]?(\(.*\))?\s*$|require ['"]|puts )`)},
+	{"typescript", regexp.MustCompile(`(?m)^\s*(export )?(interface|type) \w+\s*(=|\{)|:\s*(string|number|boolean)\s*[;,)=]`)},
+	{"javascript", regexp.MustCompile(`(?m)^\s*(const|let|var) \w+\s*=|\bfunction\s*\w*\s*\(|=>\s*\{|\bconsole\.log\(|\brequire\(['"]`)},
+	{"makefile", regexp.MustCompile(`(?m)^[\w.-]+:.*\n\t\S`)},
+	{"css", regexp.MustCompile(`(?m)^\s*[.#@]?[\w-]+[^{\n]*\{\s*$|^\s*[\w-]+\s*:\s*[^;]+;\s*$`)},
+	{"toml", regexp.MustCompile(`(?m)^\[[\w.]+\]\s*$`)},
+	{"yaml", regexp.MustCompile(`(?m)\A(---\s*\n)?([\w-]+:( .*)?\n)+`)},
+	{"markdown", regexp.MustCompile(`(?m)^(#{1,6} \S|[*-] \S.*\n[*-] \S|` + "```" + `)`)},
+	{"bash", regexp.MustCompile(`(?m)^\s*(\$ )?(sudo |apt(-get)? |echo |export \w+=|cd |ls |curl |git )`)},
+}
+
+// Detect guesses the language of a snippet from its content. It returns one of
+// the names in Languages, or "plaintext" if nothing looks familiar.
+func Detect(content string) string {
+	trimmed := strings.TrimSpace(content)
+
+	// JSON is easy to check for properly, and would otherwise be mistaken
+	// for JavaScript.
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		if json.Valid([]byte(trimmed)) {
+			return "json"
+		}
+	}
+
+	for _, d := range detectors {
+		if d.rx.MatchString(content) {
+			return d.language
+		}
+	}
+
+	// Fall back on chroma's own analysers, but only accept languages that we
+	// offer in the forms.
+	if l := lexers.Analyse(content); l != nil {
+		for _, alias := range append([]string{l.Config().Name}, l.Config().Aliases...) {
+			if name := strings.ToLower(alias); Label(name) != "" {
+				return name
+			}
+		}
+	}
+
+	return "plaintext"
+}
+
+// extensions maps file extensions that aren't in Languages to the language
+// they usually hold.
+var extensions = map[string]string{
+	".bash":     "bash",
+	".cc":       "cpp",
+	".h":        "c",
+	".hpp":      "cpp",
+	".htm":      "html",
+	".jsx":      "javascript",
+	".markdown": "markdown",
+	".mjs":      "javascript",
+	".tsx":      "typescript",
+	".yml":      "yaml",
+}
+
+// DetectFile guesses the language of a file from its name, falling back on
+// Detect() when the name doesn't give it away.
+func DetectFile(name, content string) string {
+	lower := strings.ToLower(name)
+
+	switch {
+	case lower == "dockerfile" || strings.HasPrefix(lower, "dockerfile."):
+		return "dockerfile"
+	case lower == "makefile" || lower == "gnumakefile":
+		return "makefile"
+	}
+
+	ext := path.Ext(lower)
+	if ext != "" {
+		for _, l := range Languages {
+			if l.Ext == ext {
+				return l.Name
+			}
+		}
+		if language, ok := extensions[ext]; ok {
+			return language
+		}
+	}
+
+	return Detect(content)
+}
+
+// newFormatter returns a formatter which renders tokens as a table with one
+// row per line of code. Each line number is a link to "#<prefix><n>", so that
+// a line can be shared by URL.
+func newFormatter(prefix string) *html.Formatter {
+	return html.New(
+		html.WithClasses(true),
+		html.WithLineNumbers(true),
+		html.LineNumbersInTable(true),
+		html.WithLinkableLineNumbers(true, prefix),
+	)
+}
+
+// formatter is used for snippets with a single file, whose lines are linked
+// as "#L<n>".
+var formatter = newFormatter("L")
+
+// Style is the colour scheme used for highlight.css.
+var Style = styles.Get("github")
+
+// HTML returns the content highlighted as the given language. Unknown
+// languages are rendered as plain text.
+func HTML(content, language string) (template.HTML, error) {
+	return format(formatter, content, language)
+}
+
+// HTMLPrefix is like HTML, but links line numbers as "#<prefix><n>" instead
+// of "#L<n>". It's used to keep the anchors in each file of a multi-file
+// snippet apart.
+func HTMLPrefix(content, language, prefix string) (template.HTML, error) {
+	return format(newFormatter(prefix), content, language)
+}
+
+func format(f *html.Formatter, content, language string) (template.HTML, error) {
+	lexer := lexers.Get(language)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+
+	iterator, err := lexer.Tokenise(nil, content)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+
+	err = f.Format(&buf, Style, iterator)
+	if err != nil {
+		return "", err
+	}
+
+	// The formatter escapes the content itself, so its output is safe to use
+	// as-is.
+	return template.HTML(buf.String()), nil
+}
+
+// CSS writes the stylesheet matching the classes used by HTML(). We keep its
+// output in ui/static/css/highlight.css.
+func CSS(w io.Writer) error {
+	return formatter.WriteCSS(w, Style)
+}
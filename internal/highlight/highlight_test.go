@@ -0,0 +1,167 @@
+package highlight
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Baytancha/snip56/internal/assert"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "Go",
+			content: "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n",
+			want:    "go",
+		},
+		{
+			name:    "Python",
+			content: "import os\n\ndef main():\n    print(os.getcwd())\n",
+			want:    "python",
+		},
+		{
+			name:    "Shebang",
+			content: "#!/usr/bin/env bash\nset -e\n",
+			want:    "bash",
+		},
+		{
+			name:    "SQL",
+			content: "SELECT id, title FROM snippets WHERE id = 1;",
+			want:    "sql",
+		},
+		{
+			name:    "JSON",
+			content: `{"title": "An old silent pond", "expires": 7}`,
+			want:    "json",
+		},
+		{
+			name:    "Rust",
+			content: "fn main() {\n    let mut n = 1;\n}\n",
+			want:    "rust",
+		},
+		{
+			name:    "C++",
+			content: "#include <iostream>\n\nint main() { std::cout << 1; }\n",
+			want:    "cpp",
+		},
+		{
+			name:    "HTML",
+			content: "<!doctype html>\n<html><body></body></html>\n",
+			want:    "html",
+		},
+		{
+			name:    "Dockerfile",
+			content: "FROM golang:1.21 AS build\nRUN go build ./...\n",
+			want:    "dockerfile",
+		},
+		{
+			name:    "Prose",
+			content: "An old silent pond...\nA frog jumps into the pond,\nsplash! Silence again.",
+			want:    "plaintext",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, Detect(tt.content), tt.want)
+		})
+	}
+}
+
+func TestDetectKnown(t *testing.T) {
+	// Whatever Detect() returns must be something the forms accept.
+	for _, content := range []string{"", "x", "<?php echo 1;", "body {\n  color: red;\n}\n"} {
+		assert.Equal(t, Label(Detect(content)) != "", true)
+	}
+}
+
+func TestDetectFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		want     string
+	}{
+		{
+			name:     "Extension",
+			filename: "main.go",
+			content:  "An old silent pond...",
+			want:     "go",
+		},
+		{
+			name:     "Other extension",
+			filename: "compose.yml",
+			content:  "services:\n  web:\n    image: snippetbox\n",
+			want:     "yaml",
+		},
+		{
+			name:     "Dockerfile",
+			filename: "Dockerfile",
+			content:  "RUN go build ./...\n",
+			want:     "dockerfile",
+		},
+		{
+			name:     "Makefile",
+			filename: "Makefile",
+			content:  "all:\n\tgo build\n",
+			want:     "makefile",
+		},
+		{
+			name:     "Unknown extension",
+			filename: "init.d",
+			content:  "#!/bin/sh\necho hi\n",
+			want:     "bash",
+		},
+		{
+			name:     "No extension",
+			filename: "README",
+			content:  "An old silent pond...",
+			want:     "plaintext",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, DetectFile(tt.filename, tt.content), tt.want)
+		})
+	}
+}
+
+func TestHTML(t *testing.T) {
+	t.Run("Line anchors", func(t *testing.T) {
+		html, err := HTML("one\ntwo\n", "plaintext")
+		assert.NilError(t, err)
+		assert.StringContains(t, string(html), `id="L2"`)
+		assert.StringContains(t, string(html), `href="#L2"`)
+	})
+
+	t.Run("Prefixed anchors", func(t *testing.T) {
+		html, err := HTMLPrefix("one\ntwo\n", "plaintext", "main.go-L")
+		assert.NilError(t, err)
+		assert.StringContains(t, string(html), `id="main.go-L2"`)
+		assert.StringContains(t, string(html), `href="#main.go-L2"`)
+	})
+
+	t.Run("Escapes content", func(t *testing.T) {
+		html, err := HTML("<script>alert(1)</script>", "html")
+		assert.NilError(t, err)
+		assert.Equal(t, strings.Contains(string(html), "<script>"), false)
+	})
+
+	t.Run("No inline styles", func(t *testing.T) {
+		html, err := HTML("package main\n", "go")
+		assert.NilError(t, err)
+		assert.StringContains(t, string(html), `class="kn"`)
+		assert.Equal(t, strings.Contains(string(html), "style="), false)
+	})
+
+	t.Run("Unknown language", func(t *testing.T) {
+		html, err := HTML("a < b", "klingon")
+		assert.NilError(t, err)
+		assert.StringContains(t, string(html), "a &lt; b")
+	})
+}
@@ -0,0 +1,277 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Define a Collection type to hold a named group of snippets put together by
+// a user. A snippet can be in any number of collections, and the owner decides
+// the order they're listed in.
+type Collection struct {
+	ID          int
+	UserID      int
+	Author      string // name of the user who owns the collection
+	Name        string
+	Description string
+	Visibility  string // one of the Visibility* constants
+	Size        int    // number of snippets in the collection
+	Created     time.Time
+	Modified    time.Time
+}
+
+// CollectionInput holds the user-editable fields of a collection, as passed
+// to Insert() and Update().
+type CollectionInput struct {
+	Name        string
+	Description string
+	Visibility  string
+}
+
+type CollectionModelInterface interface {
+	Insert(userID int, in CollectionInput) (int, error)
+	Get(id int, viewerID int) (*Collection, error)
+	Update(id int, in CollectionInput) error
+	Delete(id int) error
+	ForUser(userID int) ([]*Collection, error)
+	Snippets(id int, viewerID int) ([]*Snippet, error)
+	AddSnippet(id int, snippetID int) error
+	RemoveSnippet(id int, snippetID int) error
+	MoveSnippet(id int, snippetID int, up bool) error
+}
+
+// Define a CollectionModel type which wraps a sql.DB connection pool.
+type CollectionModel struct {
+	DB *sql.DB
+}
+
+// collectionColumns are the columns selected by queries returning whole
+// collections, in the order expected by Collection.dest(). The queries alias
+// the collections table as c and the users table as u.
+const collectionColumns = `c.id, c.user_id, u.name, c.name, c.description, c.visibility,
+    (SELECT COUNT(*) FROM collection_snippets cs WHERE cs.collection_id = c.id), c.created, c.modified`
+
+func (c *Collection) dest() []any {
+	return []any{&c.ID, &c.UserID, &c.Author, &c.Name, &c.Description, &c.Visibility,
+		&c.Size, &c.Created, &c.Modified}
+}
+
+// This will create a new, empty collection owned by the given user.
+func (m *CollectionModel) Insert(userID int, in CollectionInput) (int, error) {
+	stmt := `INSERT INTO collections (user_id, name, description, visibility, created, modified)
+    VALUES(?, ?, ?, ?, UTC_TIMESTAMP(), UTC_TIMESTAMP())`
+
+	result, err := m.DB.Exec(stmt, userID, in.Name, in.Description, in.Visibility)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// This will return a collection. Private collections are only returned when
+// viewerID is their owner; pass 0 for an anonymous viewer.
+func (m *CollectionModel) Get(id int, viewerID int) (*Collection, error) {
+	stmt := `SELECT ` + collectionColumns + `
+    FROM collections c INNER JOIN users u ON u.id = c.user_id
+    WHERE c.id = ? AND (c.visibility <> 'private' OR c.user_id = ?)`
+
+	c := &Collection{}
+
+	err := m.DB.QueryRow(stmt, id, viewerID).Scan(c.dest()...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// This will change the name, description and visibility of a collection.
+func (m *CollectionModel) Update(id int, in CollectionInput) error {
+	stmt := `UPDATE collections SET name = ?, description = ?, visibility = ?, modified = UTC_TIMESTAMP()
+    WHERE id = ?`
+
+	_, err := m.DB.Exec(stmt, in.Name, in.Description, in.Visibility, id)
+	return err
+}
+
+// This will delete a collection for good. The snippets in it are left alone.
+func (m *CollectionModel) Delete(id int) error {
+	result, err := m.DB.Exec("DELETE FROM collections WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// This will return all of a user's collections, in alphabetical order.
+func (m *CollectionModel) ForUser(userID int) ([]*Collection, error) {
+	stmt := `SELECT ` + collectionColumns + `
+    FROM collections c INNER JOIN users u ON u.id = c.user_id
+    WHERE c.user_id = ? ORDER BY c.name, c.id`
+
+	rows, err := m.DB.Query(stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := []*Collection{}
+
+	for rows.Next() {
+		c := &Collection{}
+		err = rows.Scan(c.dest()...)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return collections, nil
+}
+
+// This will return the live snippets in a collection, in the owner's order.
+// Snippets by other users are only included if they're public, so that an
+// unlisted snippet can't be browsed to from someone else's collection.
+// Burn-after-reading snippets are left out, as they are from every listing.
+func (m *CollectionModel) Snippets(id int, viewerID int) ([]*Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + `
+    FROM collection_snippets cs INNER JOIN snippets s ON s.id = cs.snippet_id
+    INNER JOIN users u ON u.id = s.user_id
+    WHERE cs.collection_id = ? AND (s.expires IS NULL OR s.expires > UTC_TIMESTAMP()) AND s.deleted IS NULL
+    AND (s.visibility = 'public' OR s.user_id = ?) AND NOT s.burn
+    ORDER BY cs.position`
+
+	rows, err := m.DB.Query(stmt, id, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snippets := []*Snippet{}
+
+	for rows.Next() {
+		s := &Snippet{}
+		err = rows.Scan(s.dest()...)
+		if err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return snippets, nil
+}
+
+// This will add a snippet to the end of a collection. Adding a snippet that's
+// already there has no effect.
+func (m *CollectionModel) AddSnippet(id int, snippetID int) error {
+	stmt := `INSERT IGNORE INTO collection_snippets (collection_id, snippet_id, position)
+    SELECT ?, ?, COALESCE(MAX(position), 0) + 1 FROM collection_snippets WHERE collection_id = ?`
+
+	_, err := m.DB.Exec(stmt, id, snippetID, id)
+	return err
+}
+
+// This will take a snippet out of a collection.
+func (m *CollectionModel) RemoveSnippet(id int, snippetID int) error {
+	result, err := m.DB.Exec("DELETE FROM collection_snippets WHERE collection_id = ? AND snippet_id = ?", id, snippetID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// This will swap a snippet with the live snippet just before it in a
+// collection (or just after it, if up is false). Moving the first snippet up
+// or the last one down does nothing.
+func (m *CollectionModel) MoveSnippet(id int, snippetID int, up bool) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var position int
+
+	stmt := `SELECT position FROM collection_snippets
+    WHERE collection_id = ? AND snippet_id = ? FOR UPDATE`
+
+	err = tx.QueryRow(stmt, id, snippetID).Scan(&position)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return err
+	}
+
+	// Only fixed SQL fragments are picked here.
+	cmp, order := ">", "ASC"
+	if up {
+		cmp, order = "<", "DESC"
+	}
+
+	stmt = `SELECT cs.snippet_id, cs.position FROM collection_snippets cs
+    INNER JOIN snippets s ON s.id = cs.snippet_id
+    WHERE cs.collection_id = ? AND cs.position ` + cmp + ` ?
+    AND (s.expires IS NULL OR s.expires > UTC_TIMESTAMP()) AND s.deleted IS NULL
+    ORDER BY cs.position ` + order + ` LIMIT 1 FOR UPDATE`
+
+	var otherID, otherPosition int
+
+	err = tx.QueryRow(stmt, id, position).Scan(&otherID, &otherPosition)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	stmt = `UPDATE collection_snippets SET position = ? WHERE collection_id = ? AND snippet_id = ?`
+
+	_, err = tx.Exec(stmt, otherPosition, id, snippetID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(stmt, position, id, otherID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
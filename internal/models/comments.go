@@ -0,0 +1,167 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Define a Comment type to hold a comment left on a snippet. Comments form
+// threads: a reply's ParentID is the comment it answers, and top-level
+// comments have a ParentID of 0.
+type Comment struct {
+	ID        int
+	SnippetID int
+	UserID    int
+	Author    string // name of the user who wrote the comment
+	ParentID  int
+	Body      string
+	Created   time.Time
+	// Deleted comments are only returned when they still have replies, with
+	// their Body emptied, so that the rest of the thread makes sense.
+	Deleted bool
+	// Depth is how deeply the comment is nested, starting at 0 for top-level
+	// comments. It's filled in by ForSnippet().
+	Depth int
+}
+
+type CommentModelInterface interface {
+	Insert(snippetID, userID, parentID int, body string) (int, error)
+	Get(id int) (*Comment, error)
+	ForSnippet(snippetID int) ([]*Comment, error)
+	Delete(id int) error
+}
+
+// Define a CommentModel type which wraps a sql.DB connection pool.
+type CommentModel struct {
+	DB *sql.DB
+}
+
+// This will add a comment to a snippet, as a reply to parentID if it isn't 0.
+// Checking that the parent is on the same snippet is left to the handler.
+func (m *CommentModel) Insert(snippetID, userID, parentID int, body string) (int, error) {
+	var parent any
+	if parentID != 0 {
+		parent = parentID
+	}
+
+	stmt := `INSERT INTO comments (snippet_id, user_id, parent_id, body, created)
+    VALUES(?, ?, ?, ?, UTC_TIMESTAMP())`
+
+	result, err := m.DB.Exec(stmt, snippetID, userID, parent, body)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// This will return a comment which hasn't been deleted.
+func (m *CommentModel) Get(id int) (*Comment, error) {
+	stmt := `SELECT c.id, c.snippet_id, c.user_id, u.name, COALESCE(c.parent_id, 0), c.body, c.created, c.deleted IS NOT NULL
+    FROM comments c INNER JOIN users u ON u.id = c.user_id
+    WHERE c.deleted IS NULL AND c.id = ?`
+
+	c := &Comment{}
+
+	err := m.DB.QueryRow(stmt, id).Scan(&c.ID, &c.SnippetID, &c.UserID, &c.Author, &c.ParentID, &c.Body, &c.Created, &c.Deleted)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// This will return the comments on a snippet in thread order: each comment is
+// followed by its replies, oldest first, with Depth set for indenting them.
+func (m *CommentModel) ForSnippet(snippetID int) ([]*Comment, error) {
+	stmt := `SELECT c.id, c.snippet_id, c.user_id, u.name, COALESCE(c.parent_id, 0), c.body, c.created, c.deleted IS NOT NULL
+    FROM comments c INNER JOIN users u ON u.id = c.user_id
+    WHERE c.snippet_id = ? ORDER BY c.created, c.id`
+
+	rows, err := m.DB.Query(stmt, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []*Comment{}
+
+	for rows.Next() {
+		c := &Comment{}
+		err = rows.Scan(&c.ID, &c.SnippetID, &c.UserID, &c.Author, &c.ParentID, &c.Body, &c.Created, &c.Deleted)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return Thread(comments), nil
+}
+
+// This will soft-delete a comment, so that any replies to it stay in place.
+func (m *CommentModel) Delete(id int) error {
+	stmt := `UPDATE comments SET deleted = UTC_TIMESTAMP() WHERE deleted IS NULL AND id = ?`
+
+	result, err := m.DB.Exec(stmt, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// Thread puts comments, given oldest first, into thread order and sets their
+// Depth. Deleted comments are left out unless they have replies which
+// aren't, in which case their Body is emptied.
+func Thread(comments []*Comment) []*Comment {
+	replies := map[int][]*Comment{}
+	for _, c := range comments {
+		replies[c.ParentID] = append(replies[c.ParentID], c)
+	}
+
+	var walk func(parentID, depth int) []*Comment
+	walk = func(parentID, depth int) []*Comment {
+		var thread []*Comment
+		for _, c := range replies[parentID] {
+			below := walk(c.ID, depth+1)
+			if c.Deleted {
+				if len(below) == 0 {
+					continue
+				}
+				c.Body = ""
+			}
+			c.Depth = depth
+			thread = append(thread, c)
+			thread = append(thread, below...)
+		}
+		return thread
+	}
+
+	thread := walk(0, 0)
+	if thread == nil {
+		thread = []*Comment{}
+	}
+
+	return thread
+}
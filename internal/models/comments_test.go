@@ -0,0 +1,38 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/Baytancha/snip56/internal/assert"
+)
+
+func TestThread(t *testing.T) {
+	comments := []*Comment{
+		{ID: 1, Body: "first"},
+		{ID: 2, Body: "second"},
+		{ID: 3, ParentID: 1, Body: "reply to first"},
+		{ID: 4, ParentID: 3, Body: "reply to reply"},
+		{ID: 5, ParentID: 2, Body: "deleted reply", Deleted: true},
+		{ID: 6, Body: "deleted with replies", Deleted: true},
+		{ID: 7, ParentID: 6, Body: "orphan"},
+	}
+
+	thread := Thread(comments)
+
+	var ids, depths []int
+	for _, c := range thread {
+		ids = append(ids, c.ID)
+		depths = append(depths, c.Depth)
+	}
+
+	assert.Equal(t, len(ids), 6)
+	for i, want := range []int{1, 3, 4, 2, 6, 7} {
+		assert.Equal(t, ids[i], want)
+	}
+	for i, want := range []int{0, 1, 2, 0, 0, 1} {
+		assert.Equal(t, depths[i], want)
+	}
+
+	// The deleted comment is only kept as a placeholder.
+	assert.Equal(t, thread[4].Body, "")
+}
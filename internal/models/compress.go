@@ -0,0 +1,86 @@
+package models
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// searchablePrefix is how much of a compressed piece of content is also kept
+// uncompressed, in bytes. That part is in the full-text index and is what
+// listings show, so they never need to unzip anything.
+const searchablePrefix = 16 << 10
+
+// compressContent works out what to store for a piece of content when content
+// over threshold bytes is compressed: either the content as it is and no
+// compressed copy, or its first searchablePrefix bytes and the gzipped
+// content. A threshold of 0 turns compression off.
+func compressContent(content string, threshold int) (string, []byte, error) {
+	if threshold <= 0 || len(content) <= threshold {
+		return content, nil, nil
+	}
+
+	var buf bytes.Buffer
+
+	zw := gzip.NewWriter(&buf)
+
+	_, err := io.WriteString(zw, content)
+	if err != nil {
+		return "", nil, err
+	}
+
+	err = zw.Close()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return prefix(content, searchablePrefix), buf.Bytes(), nil
+}
+
+// prefix returns at most the first n bytes of s, without splitting a
+// character.
+func prefix(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n]
+}
+
+// gzipContent scans a nullable compressed content column. When the column
+// holds anything it's unzipped into the string, replacing the searchable
+// prefix scanned before it; NULL leaves the string alone.
+type gzipContent struct {
+	s *string
+}
+
+func (g gzipContent) Scan(value any) error {
+	if value == nil {
+		return nil
+	}
+
+	b, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("models: cannot scan %T into compressed content", value)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	content, err := io.ReadAll(zr)
+	if err != nil {
+		return err
+	}
+
+	*g.s = string(content)
+	return nil
+}
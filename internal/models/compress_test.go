@@ -0,0 +1,63 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Baytancha/snip56/internal/assert"
+)
+
+func TestCompressContent(t *testing.T) {
+	small := "An old silent pond..."
+	large := strings.Repeat("A frog jumps into the pond, splash! ", 100)
+
+	// Compression off.
+	content, gz, err := compressContent(large, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, content, large)
+	assert.Equal(t, gz == nil, true)
+
+	// Under the threshold.
+	content, gz, err = compressContent(small, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, content, small)
+	assert.Equal(t, gz == nil, true)
+
+	// Over the threshold the content is compressed, keeping only a searchable
+	// prefix uncompressed, and scanning the compressed column gets it back.
+	huge := strings.Repeat(large, 10)
+	content, gz, err = compressContent(huge, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, content, huge[:searchablePrefix])
+	assert.Equal(t, len(gz) < len(huge), true)
+
+	err = gzipContent{&content}.Scan(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, content, huge)
+
+	// NULL leaves the plain content alone.
+	content = small
+	err = gzipContent{&content}.Scan(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, content, small)
+}
+
+func TestPrefix(t *testing.T) {
+	assert.Equal(t, prefix("pond", 10), "pond")
+	assert.Equal(t, prefix("pond", 2), "po")
+
+	// "古池" is two three-byte characters, so the second can't be cut in half.
+	assert.Equal(t, prefix("古池", 5), "古")
+	assert.Equal(t, prefix("古池", 6), "古池")
+	assert.Equal(t, prefix("古池", 2), "")
+}
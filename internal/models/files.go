@@ -0,0 +1,109 @@
+package models
+
+import "database/sql"
+
+// MaxFiles is the most files a snippet can hold, counting its main file.
+const MaxFiles = 10
+
+// Define a File type to hold one of the named files of a multi-file snippet.
+// A snippet's main file is kept in the snippets table itself, so that search
+// works on it as before; any further files are kept in snippet_files, and
+// copied to snippet_revision_files when the snippet is edited.
+type File struct {
+	Name     string
+	Language string
+	Content  string
+}
+
+// AllFiles returns the snippet's main file followed by its other files.
+func (s *Snippet) AllFiles() []*File {
+	main := &File{Name: s.Filename, Language: s.Language, Content: s.Content}
+	return append([]*File{main}, s.Files...)
+}
+
+// File returns the file with the given name, or nil if the snippet doesn't
+// have one.
+func (s *Snippet) File(name string) *File {
+	for _, f := range s.AllFiles() {
+		if f.Name != "" && f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx.
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// snippetFiles returns the files of a snippet other than its main one, in the
+// order they were added.
+func snippetFiles(q queryer, snippetID int) ([]*File, error) {
+	stmt := `SELECT name, language, content, content_gz FROM snippet_files
+    WHERE snippet_id = ? ORDER BY position`
+
+	return queryFiles(q, stmt, snippetID)
+}
+
+// revisionFiles returns the files other than the main one that a snippet had
+// when a revision was saved, in the same order.
+func revisionFiles(q queryer, revisionID int) ([]*File, error) {
+	stmt := `SELECT name, language, content, content_gz FROM snippet_revision_files
+    WHERE revision_id = ? ORDER BY position`
+
+	return queryFiles(q, stmt, revisionID)
+}
+
+// queryFiles runs a query selecting name, language, content and content_gz
+// and returns the files it finds.
+func queryFiles(q queryer, stmt string, args ...any) ([]*File, error) {
+	rows, err := q.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := []*File{}
+
+	for rows.Next() {
+		f := &File{}
+		err = rows.Scan(&f.Name, &f.Language, &f.Content, gzipContent{&f.Content})
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// setFiles replaces the files of a snippet other than its main one,
+// compressing any over compressOver bytes.
+func setFiles(tx *sql.Tx, snippetID int, files []File, compressOver int) error {
+	_, err := tx.Exec("DELETE FROM snippet_files WHERE snippet_id = ?", snippetID)
+	if err != nil {
+		return err
+	}
+
+	stmt := `INSERT INTO snippet_files (snippet_id, position, name, language, content, content_gz)
+    VALUES (?, ?, ?, ?, ?, ?)`
+
+	for i, f := range files {
+		content, contentGz, err := compressContent(f.Content, compressOver)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(stmt, snippetID, i+1, f.Name, f.Language, content, contentGz)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
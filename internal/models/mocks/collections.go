@@ -0,0 +1,111 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/Baytancha/snip56/internal/models"
+)
+
+// mockCollection belongs to the first mock user and holds mockSnippet and
+// mockOtherSnippet.
+var mockCollection = &models.Collection{
+	ID:          1,
+	UserID:      1,
+	Author:      "Alice Jones",
+	Name:        "Favourite haiku",
+	Description: "Short poems about nature",
+	Visibility:  models.VisibilityPublic,
+	Size:        2,
+	Created:     time.Now(),
+	Modified:    time.Now(),
+}
+
+// mockPrivateCollection belongs to another user, who keeps it to themselves.
+var mockPrivateCollection = &models.Collection{
+	ID:         2,
+	UserID:     2,
+	Author:     "Bob Smith",
+	Name:       "Drafts",
+	Visibility: models.VisibilityPrivate,
+	Created:    time.Now(),
+	Modified:   time.Now(),
+}
+
+// mockOtherCollection belongs to another user and can be seen by anyone.
+var mockOtherCollection = &models.Collection{
+	ID:         3,
+	UserID:     2,
+	Author:     "Bob Smith",
+	Name:       "Winter",
+	Visibility: models.VisibilityPublic,
+	Created:    time.Now(),
+	Modified:   time.Now(),
+}
+
+type CollectionModel struct{}
+
+func (m *CollectionModel) Insert(userID int, in models.CollectionInput) (int, error) {
+	return 4, nil
+}
+
+func (m *CollectionModel) Get(id int, viewerID int) (*models.Collection, error) {
+	switch {
+	case id == 1:
+		return mockCollection, nil
+	case id == 2 && viewerID == mockPrivateCollection.UserID:
+		return mockPrivateCollection, nil
+	case id == 3:
+		return mockOtherCollection, nil
+	default:
+		return nil, models.ErrNoRecord
+	}
+}
+
+func (m *CollectionModel) Update(id int, in models.CollectionInput) error {
+	return nil
+}
+
+func (m *CollectionModel) Delete(id int) error {
+	switch id {
+	case 1, 2, 3:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+func (m *CollectionModel) ForUser(userID int) ([]*models.Collection, error) {
+	switch userID {
+	case 1:
+		return []*models.Collection{mockCollection}, nil
+	default:
+		return []*models.Collection{}, nil
+	}
+}
+
+func (m *CollectionModel) Snippets(id int, viewerID int) ([]*models.Snippet, error) {
+	switch id {
+	case 1:
+		return []*models.Snippet{mockSnippet, mockOtherSnippet}, nil
+	default:
+		return []*models.Snippet{}, nil
+	}
+}
+
+func (m *CollectionModel) AddSnippet(id int, snippetID int) error {
+	return nil
+}
+
+func (m *CollectionModel) RemoveSnippet(id int, snippetID int) error {
+	if id == 1 && (snippetID == 1 || snippetID == 3) {
+		return nil
+	}
+	return models.ErrNoRecord
+}
+
+func (m *CollectionModel) MoveSnippet(id int, snippetID int, up bool) error {
+	if id == 1 && (snippetID == 1 || snippetID == 3) {
+		return nil
+	}
+	return models.ErrNoRecord
+}
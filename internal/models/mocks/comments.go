@@ -0,0 +1,79 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/Baytancha/snip56/internal/models"
+)
+
+// mockComment was left by another user on the first mock user's snippet, so
+// that user may delete it as the snippet's owner.
+var mockComment = &models.Comment{
+	ID:        1,
+	SnippetID: 1,
+	UserID:    2,
+	Author:    "Bob Smith",
+	Body:      "What a **lovely** <haiku>",
+	Created:   time.Now(),
+}
+
+// mockReply is the first mock user's answer to mockComment.
+var mockReply = &models.Comment{
+	ID:        2,
+	SnippetID: 1,
+	UserID:    1,
+	Author:    "Alice Jones",
+	ParentID:  1,
+	Body:      "Thank you!",
+	Created:   time.Now(),
+	Depth:     1,
+}
+
+// mockOtherComment was left by another user on their own snippet.
+var mockOtherComment = &models.Comment{
+	ID:        3,
+	SnippetID: 3,
+	UserID:    2,
+	Author:    "Bob Smith",
+	Body:      "Winter is coming",
+	Created:   time.Now(),
+}
+
+type CommentModel struct{}
+
+func (m *CommentModel) Insert(snippetID, userID, parentID int, body string) (int, error) {
+	return 4, nil
+}
+
+func (m *CommentModel) Get(id int) (*models.Comment, error) {
+	switch id {
+	case 1:
+		return mockComment, nil
+	case 2:
+		return mockReply, nil
+	case 3:
+		return mockOtherComment, nil
+	default:
+		return nil, models.ErrNoRecord
+	}
+}
+
+func (m *CommentModel) ForSnippet(snippetID int) ([]*models.Comment, error) {
+	switch snippetID {
+	case 1:
+		return []*models.Comment{mockComment, mockReply}, nil
+	case 3:
+		return []*models.Comment{mockOtherComment}, nil
+	default:
+		return []*models.Comment{}, nil
+	}
+}
+
+func (m *CommentModel) Delete(id int) error {
+	switch id {
+	case 1, 2, 3:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
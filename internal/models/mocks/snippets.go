@@ -1,29 +1,221 @@
 package mocks
 
 import (
+	"strings"
 	"time"
 
 	"github.com/Baytancha/snip56/internal/models"
+	"golang.org/x/crypto/bcrypt"
 )
 
 var mockSnippet = &models.Snippet{
-	ID:      1,
-	Title:   "An old silent pond",
-	Content: "An old silent pond...",
-	Created: time.Now(),
-	Expires: time.Now(),
+	ID:         1,
+	PublicID:   "aB3xK9mQ",
+	UserID:     1,
+	Author:     "Alice Jones",
+	Title:      "An old silent pond",
+	Content:    "An old silent pond...",
+	Language:   "plaintext",
+	Format:     models.FormatCode,
+	Visibility: models.VisibilityPublic,
+	Tags:       []string{"haiku", "nature"},
+	Created:    time.Now(),
+	Modified:   time.Now(),
+	Expires:    time.Now(),
+}
+
+// mockDeletedSnippet has been soft-deleted, so Get() no longer finds it but an
+// admin can still restore it.
+var mockDeletedSnippet = &models.Snippet{
+	ID:         2,
+	PublicID:   "zT1uB5eG",
+	UserID:     1,
+	Author:     "Alice Jones",
+	Title:      "The first cold shower",
+	Content:    "The first cold shower...",
+	Format:     models.FormatCode,
+	Visibility: models.VisibilityPublic,
+	Created:    time.Now(),
+	Modified:   time.Now(),
+	Expires:    time.Now(),
+}
+
+// mockOtherSnippet belongs to a user other than the logged-in mock user, so it
+// can be used to check ownership rules. The first mock user has starred it.
+var mockOtherSnippet = &models.Snippet{
+	ID:         3,
+	PublicID:   "wF7nT2cR",
+	UserID:     2,
+	Author:     "Bob Smith",
+	Title:      "Over the wintry forest",
+	Content:    "Over the wintry forest, winds howl in rage...",
+	Format:     models.FormatCode,
+	Visibility: models.VisibilityPublic,
+	Stars:      1,
+	Created:    time.Now(),
+	Modified:   time.Now(),
+	Expires:    time.Now(),
+}
+
+// mockForkSnippet is another user's fork of mockSnippet.
+var mockForkSnippet = &models.Snippet{
+	ID:         7,
+	PublicID:   "fK2qX7hD",
+	UserID:     2,
+	Author:     "Bob Smith",
+	ParentID:   1,
+	Title:      "An old silent pond (remix)",
+	Content:    "An old silent pond... a frog jumps in",
+	Language:   "plaintext",
+	Format:     models.FormatCode,
+	Visibility: models.VisibilityPublic,
+	Created:    time.Now(),
+	Modified:   time.Now(),
+	Expires:    time.Now(),
+}
+
+// mockFilesSnippet has a second file besides its main one.
+var mockFilesSnippet = &models.Snippet{
+	ID:         8,
+	PublicID:   "dC8gV1zP",
+	UserID:     1,
+	Author:     "Alice Jones",
+	Title:      "Snippetbox in Docker",
+	Filename:   "Dockerfile",
+	Content:    "FROM golang:1.21\nRUN go build ./cmd/web\n",
+	Language:   "dockerfile",
+	Format:     models.FormatCode,
+	Visibility: models.VisibilityPublic,
+	Files: []*models.File{
+		{Name: "compose.yaml", Language: "yaml", Content: "services:\n  web:\n    build: .\n"},
+	},
+	Created:  time.Now(),
+	Modified: time.Now(),
+	Expires:  time.Now(),
+}
+
+// mockMarkdownSnippet is a note written in Markdown, with some HTML that must
+// not make it onto the page.
+var mockMarkdownSnippet = &models.Snippet{
+	ID:         9,
+	PublicID:   "mN6jY4sL",
+	UserID:     1,
+	Author:     "Alice Jones",
+	Title:      "Release notes",
+	Content:    "# Release notes\n\n- **Faster** search\n- See [the docs](https://example.com/docs)\n\n<script>alert(1)</script>\n",
+	Language:   "markdown",
+	Format:     models.FormatMarkdown,
+	Visibility: models.VisibilityPublic,
+	Created:    time.Now(),
+	Modified:   time.Now(),
+	Expires:    time.Now(),
+}
+
+// mockPrivateSnippet is only visible to its author, the first mock user.
+var mockPrivateSnippet = &models.Snippet{
+	ID:         4,
+	PublicID:   "pL5vH8dZ",
+	UserID:     1,
+	Author:     "Alice Jones",
+	Title:      "A lightning flash",
+	Content:    "A lightning flash: between the forest trees...",
+	Language:   "plaintext",
+	Format:     models.FormatCode,
+	Visibility: models.VisibilityPrivate,
+	Created:    time.Now(),
+	Modified:   time.Now(),
+	Expires:    time.Now(),
+}
+
+// mockProtectedSnippet belongs to another user and can only be seen after
+// entering its password, "open sesame".
+var mockProtectedSnippet = &models.Snippet{
+	ID:             5,
+	PublicID:       "sE4kJ6yN",
+	UserID:         2,
+	Author:         "Bob Smith",
+	Title:          "Database settings",
+	Content:        "dsn: web:pass@/snippetbox",
+	Language:       "yaml",
+	Format:         models.FormatCode,
+	Visibility:     models.VisibilityUnlisted,
+	HashedPassword: mustHash("open sesame"),
+	Created:        time.Now(),
+	Modified:       time.Now(),
+	Expires:        time.Now(),
+}
+
+// mockBurnSnippet is deleted the first time it is read.
+var mockBurnSnippet = &models.Snippet{
+	ID:         6,
+	PublicID:   "bU9rM3tW",
+	UserID:     2,
+	Author:     "Bob Smith",
+	Title:      "Wifi password",
+	Content:    "correct horse battery staple",
+	Language:   "plaintext",
+	Format:     models.FormatCode,
+	Visibility: models.VisibilityUnlisted,
+	Burn:       true,
+	Created:    time.Now(),
+	Modified:   time.Now(),
+	Expires:    time.Now(),
+}
+
+func mustHash(password string) []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		panic(err)
+	}
+	return hash
 }
 
 type SnippetModel struct{}
 
-func (m *SnippetModel) Insert(title string, content string, expires int) (int, error) {
-	return 2, nil
+func (m *SnippetModel) Insert(userID int, in models.SnippetInput) (int, string, error) {
+	return 2, "hG5tR2wX", nil
 }
 
-func (m *SnippetModel) Get(id int) (*models.Snippet, error) {
-	switch id {
-	case 1:
+func (m *SnippetModel) Get(id int, viewerID int) (*models.Snippet, error) {
+	switch {
+	case id == 1:
 		return mockSnippet, nil
+	case id == 3:
+		return mockOtherSnippet, nil
+	case id == 4 && viewerID == mockPrivateSnippet.UserID:
+		return mockPrivateSnippet, nil
+	case id == 5:
+		return mockProtectedSnippet, nil
+	case id == 6:
+		return mockBurnSnippet, nil
+	case id == 7:
+		return mockForkSnippet, nil
+	case id == 8:
+		return mockFilesSnippet, nil
+	case id == 9:
+		return mockMarkdownSnippet, nil
+	default:
+		return nil, models.ErrNoRecord
+	}
+}
+
+// GetByPublicID finds the mock snippets by public ID, following the same rules
+// as Get().
+func (m *SnippetModel) GetByPublicID(publicID string, viewerID int) (*models.Snippet, error) {
+	for _, s := range []*models.Snippet{mockSnippet, mockOtherSnippet, mockPrivateSnippet, mockProtectedSnippet,
+		mockBurnSnippet, mockForkSnippet, mockFilesSnippet, mockMarkdownSnippet} {
+		if s.PublicID == publicID {
+			return m.Get(s.ID, viewerID)
+		}
+	}
+
+	return nil, models.ErrNoRecord
+}
+
+func (m *SnippetModel) Burn(id int, viewerID int) (*models.Snippet, error) {
+	switch id {
+	case 6:
+		return mockBurnSnippet, nil
 	default:
 		return nil, models.ErrNoRecord
 	}
@@ -32,3 +224,180 @@ func (m *SnippetModel) Get(id int) (*models.Snippet, error) {
 func (m *SnippetModel) Latest() ([]*models.Snippet, error) {
 	return []*models.Snippet{mockSnippet}, nil
 }
+
+func (m *SnippetModel) Forks(id int) ([]*models.Snippet, error) {
+	switch id {
+	case 1:
+		return []*models.Snippet{mockForkSnippet}, nil
+	default:
+		return []*models.Snippet{}, nil
+	}
+}
+
+func (m *SnippetModel) List(filter models.SnippetFilter, page int) ([]*models.Snippet, int, error) {
+	if filter.Tag != "" && filter.Tag != "haiku" && filter.Tag != "nature" {
+		return []*models.Snippet{}, 0, nil
+	}
+	if filter.UserID != 0 && filter.UserID != 1 {
+		return []*models.Snippet{}, 0, nil
+	}
+	if page > 1 {
+		return []*models.Snippet{}, 1, nil
+	}
+
+	return []*models.Snippet{mockSnippet}, 1, nil
+}
+
+func (m *SnippetModel) Search(query models.SearchQuery, page int) ([]*models.Snippet, int, error) {
+	for _, term := range query.Excluded {
+		if strings.Contains(strings.ToLower(mockSnippet.Content), strings.ToLower(term)) {
+			return []*models.Snippet{}, 0, nil
+		}
+	}
+
+	for _, term := range query.Terms {
+		if strings.Contains(strings.ToLower(mockSnippet.Content), strings.ToLower(term)) {
+			return []*models.Snippet{mockSnippet}, 1, nil
+		}
+	}
+
+	return []*models.Snippet{}, 0, nil
+}
+
+func (m *SnippetModel) Update(id int, in models.SnippetInput) error {
+	switch id {
+	case 1, 3:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+func (m *SnippetModel) Delete(id int) error {
+	switch id {
+	case 1, 3:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+func (m *SnippetModel) Expire(id int) error {
+	switch id {
+	case 1, 3:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+func (m *SnippetModel) Restore(id int) error {
+	switch id {
+	case 2:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+func (m *SnippetModel) Deleted() ([]*models.Snippet, error) {
+	return []*models.Snippet{mockDeletedSnippet}, nil
+}
+
+var mockRevision = &models.Revision{
+	ID:        1,
+	SnippetID: 1,
+	Title:     "An old pond",
+	Content:   "An old pond...",
+	Created:   time.Now(),
+}
+
+// mockFilesRevision is the Docker snippet before its compose file was changed.
+var mockFilesRevision = &models.Revision{
+	ID:        2,
+	SnippetID: 8,
+	Title:     "Snippetbox in Docker",
+	Filename:  "Dockerfile",
+	Content:   "FROM golang:1.21\nRUN go build ./cmd/web\n",
+	Files: []*models.File{
+		{Name: "compose.yaml", Language: "yaml", Content: "services:\n  app:\n    build: .\n"},
+	},
+	Created: time.Now(),
+}
+
+func (m *SnippetModel) Revisions(snippetID int) ([]*models.Revision, error) {
+	switch snippetID {
+	case 1:
+		return []*models.Revision{mockRevision}, nil
+	default:
+		return []*models.Revision{}, nil
+	}
+}
+
+func (m *SnippetModel) Revision(snippetID int, id int) (*models.Revision, error) {
+	switch {
+	case snippetID == 1 && id == 1:
+		return mockRevision, nil
+	case snippetID == 8 && id == 2:
+		return mockFilesRevision, nil
+	}
+
+	return nil, models.ErrNoRecord
+}
+
+func (m *SnippetModel) TagCounts(limit int) ([]*models.Tag, error) {
+	return []*models.Tag{{Name: "haiku", Count: 1}, {Name: "nature", Count: 1}}, nil
+}
+
+func (m *SnippetModel) Star(snippetID, userID int) error {
+	return nil
+}
+
+func (m *SnippetModel) Unstar(snippetID, userID int) error {
+	return nil
+}
+
+func (m *SnippetModel) Starred(snippetID, userID int) (bool, error) {
+	return snippetID == 3 && userID == 1, nil
+}
+
+func (m *SnippetModel) Stars(userID int, page int) ([]*models.Snippet, int, error) {
+	if userID != 1 {
+		return []*models.Snippet{}, 0, nil
+	}
+	if page > 1 {
+		return []*models.Snippet{}, 1, nil
+	}
+
+	return []*models.Snippet{mockOtherSnippet}, 1, nil
+}
+
+func (m *SnippetModel) StarCounts(userID int) (int, int, error) {
+	if userID == 1 {
+		return 0, 1, nil
+	}
+	return 0, 0, nil
+}
+
+func (m *SnippetModel) AddViews(views map[models.ViewKey]int) error {
+	return nil
+}
+
+// DailyViews reports a few days of views for mockSnippet, counting back
+// from today.
+func (m *SnippetModel) DailyViews(snippetID int, since time.Time) ([]*models.DailyViews, error) {
+	if snippetID != 1 {
+		return []*models.DailyViews{}, nil
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	return []*models.DailyViews{
+		{Day: today.AddDate(0, 0, -2), Views: 3},
+		{Day: today, Views: 12},
+	}, nil
+}
+
+func (m *SnippetModel) MostStarred(since time.Time, limit int) ([]*models.Snippet, error) {
+	return []*models.Snippet{mockOtherSnippet}, nil
+}
@@ -0,0 +1,86 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/Baytancha/snip56/internal/models"
+)
+
+// mockSiteTemplate is offered to every user.
+var mockSiteTemplate = &models.Template{
+	ID:       1,
+	Name:     "Incident notes",
+	Title:    "Incident: ",
+	Content:  "## Impact\n\n## Timeline\n\n## Follow-up\n",
+	Language: "markdown",
+	Format:   models.FormatMarkdown,
+	Expires:  "never",
+	Created:  time.Now(),
+	Modified: time.Now(),
+}
+
+// mockTemplate belongs to the first mock user.
+var mockTemplate = &models.Template{
+	ID:            2,
+	UserID:        1,
+	Name:          "SQL query",
+	Title:         "Query",
+	Content:       "SELECT id FROM snippets WHERE ",
+	Language:      "sql",
+	Format:        models.FormatCode,
+	Expires:       "custom",
+	ExpiresAmount: 12,
+	ExpiresUnit:   "hours",
+	Created:       time.Now(),
+	Modified:      time.Now(),
+}
+
+// mockOtherTemplate belongs to another user.
+var mockOtherTemplate = &models.Template{
+	ID:       3,
+	UserID:   2,
+	Name:     "Runbook",
+	Title:    "Runbook",
+	Content:  "1. ",
+	Format:   models.FormatPlain,
+	Expires:  "365",
+	Created:  time.Now(),
+	Modified: time.Now(),
+}
+
+type TemplateModel struct{}
+
+func (m *TemplateModel) Insert(userID int, in models.TemplateInput) (int, error) {
+	return 4, nil
+}
+
+func (m *TemplateModel) Get(id int, viewerID int) (*models.Template, error) {
+	for _, t := range []*models.Template{mockSiteTemplate, mockTemplate, mockOtherTemplate} {
+		if t.ID == id && (t.SiteWide() || t.UserID == viewerID) {
+			return t, nil
+		}
+	}
+	return nil, models.ErrNoRecord
+}
+
+func (m *TemplateModel) Update(id int, in models.TemplateInput) error {
+	return nil
+}
+
+func (m *TemplateModel) Delete(id int) error {
+	switch id {
+	case 1, 2, 3:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+func (m *TemplateModel) ForUser(userID int) ([]*models.Template, error) {
+	switch userID {
+	case 1:
+		return []*models.Template{mockSiteTemplate, mockTemplate}, nil
+	default:
+		return []*models.Template{mockSiteTemplate}, nil
+	}
+}
@@ -1,9 +1,26 @@
 package mocks
 
 import (
+	"time"
+
 	"github.com/Baytancha/snip56/internal/models"
 )
 
+var mockUser = &models.User{
+	ID:      1,
+	Name:    "Alice Jones",
+	Email:   "alice@example.com",
+	Created: time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC),
+}
+
+var mockAdmin = &models.User{
+	ID:      4,
+	Name:    "Carol Admin",
+	Email:   "admin@example.com",
+	Created: time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC),
+	Admin:   true,
+}
+
 type UserModel struct{}
 
 func (m *UserModel) Insert(name, email, password string) error {
@@ -19,13 +36,16 @@ func (m *UserModel) Authenticate(email, password string) (int, error) {
 	if email == "alice@example.com" && password == "pa$$word" {
 		return 1, nil
 	}
+	if email == "admin@example.com" && password == "pa$$word" {
+		return 4, nil
+	}
 
 	return 0, models.ErrInvalidCredentials
 }
 
 func (m *UserModel) Exists(id int) (bool, error) {
 	switch id {
-	case 1:
+	case 1, 4:
 		return true, nil
 	default:
 		return false, nil
@@ -33,9 +53,12 @@ func (m *UserModel) Exists(id int) (bool, error) {
 }
 
 func (m *UserModel) GetbyID(id int) (*models.User, error) {
-
-	user := &models.User{}
-
-	return user, nil
-
+	switch id {
+	case 1:
+		return mockUser, nil
+	case 4:
+		return mockAdmin, nil
+	default:
+		return nil, models.ErrNoRecord
+	}
 }
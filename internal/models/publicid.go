@@ -0,0 +1,69 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// PublicIDLength is the number of characters in a snippet's public ID. With
+// 62 possible characters that's over 10^14 IDs, far too many to guess.
+const PublicIDLength = 8
+
+const publicIDChars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// PublicIDRX matches anything that could be a public ID.
+var PublicIDRX = regexp.MustCompile(`^[0-9A-Za-z]{8}$`)
+
+// newPublicID returns a random base62 public ID. IDs made only of digits are
+// never returned, so a public ID can't be mistaken for a snippet's integer
+// key in a URL.
+func newPublicID() (string, error) {
+	max := big.NewInt(int64(len(publicIDChars)))
+
+	for {
+		var b strings.Builder
+
+		for i := 0; i < PublicIDLength; i++ {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return "", err
+			}
+			b.WriteByte(publicIDChars[n.Int64()])
+		}
+
+		id := b.String()
+		if strings.Trim(id, "0123456789") != "" {
+			return id, nil
+		}
+	}
+}
+
+// isDuplicatePublicID reports whether err comes from inserting a public ID
+// that's already taken.
+func isDuplicatePublicID(err error) bool {
+	var mySQLError *mysql.MySQLError
+	return errors.As(err, &mySQLError) && mySQLError.Number == 1062 &&
+		strings.Contains(mySQLError.Message, "snippets_uc_public_id")
+}
+
+// This will return the snippet with the given public ID, following the same
+// rules as Get().
+func (m *SnippetModel) GetByPublicID(publicID string, viewerID int) (*Snippet, error) {
+	var id int
+
+	err := m.DB.QueryRow(`SELECT id FROM snippets WHERE public_id = ?`, publicID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return m.Get(id, viewerID)
+}
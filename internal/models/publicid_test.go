@@ -0,0 +1,25 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Baytancha/snip56/internal/assert"
+)
+
+func TestNewPublicID(t *testing.T) {
+	seen := map[string]bool{}
+
+	for i := 0; i < 1000; i++ {
+		id, err := newPublicID()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, PublicIDRX.MatchString(id), true)
+		assert.Equal(t, strings.Trim(id, "0123456789") != "", true)
+		assert.Equal(t, seen[id], false)
+
+		seen[id] = true
+	}
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Define a Revision type to hold an earlier version of a snippet. A new one is
+// written every time a snippet is updated.
+type Revision struct {
+	ID        int
+	SnippetID int
+	Title     string
+	Filename  string // name of the main file, as with Snippet
+	Content   string
+	Files     []*File   // the files after the main one; only filled in by Revision()
+	Created   time.Time // when this version was originally saved
+}
+
+// This will return all the earlier versions of a snippet, newest first. Only
+// the searchable prefix of compressed content is returned; use Revision() for
+// all of it.
+func (m *SnippetModel) Revisions(snippetID int) ([]*Revision, error) {
+	stmt := `SELECT id, snippet_id, title, filename, content, created FROM snippet_revisions
+    WHERE snippet_id = ? ORDER BY id DESC`
+
+	rows, err := m.DB.Query(stmt, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*Revision{}
+
+	for rows.Next() {
+		r := &Revision{}
+		err = rows.Scan(&r.ID, &r.SnippetID, &r.Title, &r.Filename, &r.Content, &r.Created)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// This will return a specific earlier version of a snippet, with all its
+// files. Both IDs are checked so that a revision can't be read through another
+// snippet's URL.
+func (m *SnippetModel) Revision(snippetID int, id int) (*Revision, error) {
+	stmt := `SELECT id, snippet_id, title, filename, content, content_gz, created FROM snippet_revisions
+    WHERE snippet_id = ? AND id = ?`
+
+	r := &Revision{}
+
+	err := m.DB.QueryRow(stmt, snippetID, id).Scan(&r.ID, &r.SnippetID, &r.Title, &r.Filename, &r.Content, gzipContent{&r.Content}, &r.Created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	r.Files, err = revisionFiles(m.DB, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+)
+
+// A SearchQuery is a parsed search string. Terms are words or quoted phrases
+// that a snippet must contain; Excluded are ones it must not contain.
+type SearchQuery struct {
+	Terms    []string
+	Excluded []string
+}
+
+// ParseSearchQuery splits a search string typed by a user into terms. Phrases
+// can be grouped with double quotes, and a leading "-" excludes a word or
+// phrase, as in:
+//
+//	"connection pool" mysql -postgres
+//
+// Characters that have a special meaning in MySQL boolean full-text searches
+// are dropped, so the result is always safe to pass to BooleanMode().
+func ParseSearchQuery(s string) SearchQuery {
+	var q SearchQuery
+
+	for len(s) > 0 {
+		s = strings.TrimLeftFunc(s, unicode.IsSpace)
+		if s == "" {
+			break
+		}
+
+		exclude := false
+		if s[0] == '-' {
+			exclude = true
+			s = s[1:]
+		}
+
+		var term string
+		if strings.HasPrefix(s, `"`) {
+			// Take everything up to the closing quote, or the rest of the
+			// string if it's missing.
+			end := strings.IndexByte(s[1:], '"')
+			if end == -1 {
+				term, s = s[1:], ""
+			} else {
+				term, s = s[1:end+1], s[end+2:]
+			}
+		} else {
+			end := strings.IndexFunc(s, unicode.IsSpace)
+			if end == -1 {
+				term, s = s, ""
+			} else {
+				term, s = s[:end], s[end:]
+			}
+		}
+
+		term = strings.Join(strings.Fields(strings.Map(cleanSearchRune, term)), " ")
+		if term == "" {
+			continue
+		}
+
+		if exclude {
+			q.Excluded = append(q.Excluded, term)
+		} else {
+			q.Terms = append(q.Terms, term)
+		}
+	}
+
+	return q
+}
+
+// cleanSearchRune replaces the MySQL boolean-mode operators with spaces.
+func cleanSearchRune(r rune) rune {
+	if strings.ContainsRune(`+-<>()~*"@`, r) {
+		return ' '
+	}
+	return r
+}
+
+// BooleanMode turns the query into a MySQL boolean-mode search string in which
+// every term is required and every excluded term is forbidden.
+func (q SearchQuery) BooleanMode() string {
+	var parts []string
+
+	for _, t := range q.Terms {
+		parts = append(parts, "+"+quoteSearchTerm(t))
+	}
+	for _, t := range q.Excluded {
+		parts = append(parts, "-"+quoteSearchTerm(t))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// quoteSearchTerm wraps phrases in double quotes so MySQL matches the words
+// next to each other.
+func quoteSearchTerm(t string) string {
+	if strings.Contains(t, " ") {
+		return `"` + t + `"`
+	}
+	return t
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Baytancha/snip56/internal/assert"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		wantTerms    string
+		wantExcluded string
+		wantBoolean  string
+	}{
+		{
+			name:        "Words",
+			query:       "old  pond",
+			wantTerms:   "old|pond",
+			wantBoolean: "+old +pond",
+		},
+		{
+			name:        "Phrase",
+			query:       `"silent pond" frog`,
+			wantTerms:   "silent pond|frog",
+			wantBoolean: `+"silent pond" +frog`,
+		},
+		{
+			name:         "Excluded word and phrase",
+			query:        `pond -frog -"cold shower"`,
+			wantTerms:    "pond",
+			wantExcluded: "frog|cold shower",
+			wantBoolean:  `+pond -frog -"cold shower"`,
+		},
+		{
+			name:        "Unterminated phrase",
+			query:       `"silent pond`,
+			wantTerms:   "silent pond",
+			wantBoolean: `+"silent pond"`,
+		},
+		{
+			name:        "Operators are dropped",
+			query:       `po*nd (frog) ~@"`,
+			wantTerms:   "po nd|frog",
+			wantBoolean: `+"po nd" +frog`,
+		},
+		{
+			name:  "Empty",
+			query: "   ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := ParseSearchQuery(tt.query)
+
+			assert.Equal(t, strings.Join(q.Terms, "|"), tt.wantTerms)
+			assert.Equal(t, strings.Join(q.Excluded, "|"), tt.wantExcluded)
+			assert.Equal(t, q.BooleanMode(), tt.wantBoolean)
+		})
+	}
+}
@@ -3,43 +3,251 @@ package models
 import (
 	"database/sql"
 	"errors" // New import
+	"strings"
 	"time"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 // Define a Snippet type to hold the data for an individual snippet. Notice how
 // the fields of the struct correspond to the fields in our MySQL snippets
 // table?
 type Snippet struct {
-	ID      int
-	Title   string
-	Content string
-	Created time.Time
-	Expires time.Time
+	ID         int
+	PublicID   string // random ID used in URLs; see PublicIDLength
+	UserID     int
+	Author     string // Name of the user who created the snippet.
+	ParentID   int    // the snippet this one was forked from, or 0
+	Title      string
+	Filename   string   // name of the main file; may be empty for a single file
+	Content    string   // only the start of a compressed snippet, except from Get() and Burn()
+	Language   string   // highlighting language, see the highlight package
+	Format     string   // one of the Format* constants
+	Visibility string   // one of the Visibility* constants
+	Tags       []string // only filled in by Get()
+	Stars      int      // number of users who have starred the snippet
+	// Files holds the files after the main one, if any. It's only filled in
+	// by Get() and Burn(); see AllFiles().
+	Files []*File
+	// HashedPassword is nil unless the author protected the snippet with a
+	// password.
+	HashedPassword []byte
+	// Burn snippets are deleted the first time they are read; see Burn().
+	Burn     bool
+	Created  time.Time
+	Modified time.Time
+	Expires  time.Time // the zero time if the snippet never expires
+}
+
+// NeverExpires reports whether the snippet is kept until it's deleted.
+func (s *Snippet) NeverExpires() bool {
+	return s.Expires.IsZero()
+}
+
+// A snippet's visibility decides who can see it. Public snippets are shown
+// everywhere; unlisted ones can only be reached by someone who knows their
+// URL; private ones can only be seen by their author.
+const (
+	VisibilityPublic   = "public"
+	VisibilityUnlisted = "unlisted"
+	VisibilityPrivate  = "private"
+)
+
+// A snippet's format decides how its main file is shown. Code is syntax
+// highlighted in its language, plain text is shown as it is and Markdown is
+// rendered to HTML.
+const (
+	FormatPlain    = "plain"
+	FormatCode     = "code"
+	FormatMarkdown = "markdown"
+)
+
+// Protected reports whether the snippet needs a password to be viewed.
+func (s *Snippet) Protected() bool {
+	return s.HashedPassword != nil
+}
+
+// CheckPassword returns ErrInvalidCredentials if password doesn't unlock the
+// snippet.
+func (s *Snippet) CheckPassword(password string) error {
+	err := bcrypt.CompareHashAndPassword(s.HashedPassword, []byte(password))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrInvalidCredentials
+		}
+		return err
+	}
+
+	return nil
+}
+
+// SnippetInput holds the user-editable fields of a snippet, as passed to
+// Insert() and Update(). A zero Expires means the snippet never expires.
+//
+// Files are the snippet's files after the main one, whose name is Filename.
+// Tags replace any the snippet had, and are expected to have been validated.
+//
+// Password is stored hashed. When updating, an empty Password leaves any
+// existing password alone unless ClearPassword is set.
+type SnippetInput struct {
+	Title         string
+	Filename      string
+	Content       string
+	Language      string
+	Format        string
+	Files         []File
+	Tags          []string
+	Visibility    string
+	Password      string
+	ClearPassword bool
+	Burn          bool
+	Expires       time.Time
+	KeepExpiry    bool // leave the expiry and burn flag alone; Update() only
+	ParentID      int  // snippet being forked, if any; ignored by Update()
+}
+
+// parentID returns the value to store in the parent_id column: NULL for a
+// snippet that isn't a fork.
+func (in SnippetInput) parentID() any {
+	if in.ParentID == 0 {
+		return nil
+	}
+	return in.ParentID
+}
+
+// expires returns the value to store in the expires column: NULL for a
+// snippet that never expires.
+func (in SnippetInput) expires() any {
+	if in.Expires.IsZero() {
+		return nil
+	}
+	return in.Expires.UTC()
+}
+
+// hashedPassword returns the bcrypt hash of the input's password, or nil if it
+// doesn't have one.
+func (in SnippetInput) hashedPassword() ([]byte, error) {
+	if in.Password == "" {
+		return nil, nil
+	}
+
+	return bcrypt.GenerateFromPassword([]byte(in.Password), 12)
+}
+
+// snippetColumns are the columns selected by every query that returns whole
+// snippets, in the order expected by Snippet.dest(). The queries alias the
+// snippets table as s and the users table as u. Compressed content is left
+// out, as listings make do with its searchable prefix; Get() and Burn() add
+// snippetContentColumn to get all of it.
+const snippetColumns = `s.id, s.public_id, s.user_id, u.name, COALESCE(s.parent_id, 0), s.title, s.filename, s.content,
+    s.language, s.format, s.visibility, s.hashed_password, s.burn, s.created, s.modified, s.expires,
+    (SELECT COUNT(*) FROM stars WHERE stars.snippet_id = s.id)`
+
+// snippetContentColumn follows snippetColumns when the whole content is
+// wanted; see Snippet.fullDest().
+const snippetContentColumn = `, s.content_gz`
+
+// dest returns pointers to the fields of the snippet for scanning a row of
+// snippetColumns into.
+func (s *Snippet) dest() []any {
+	return []any{&s.ID, &s.PublicID, &s.UserID, &s.Author, &s.ParentID, &s.Title, &s.Filename, &s.Content,
+		&s.Language, &s.Format, &s.Visibility, &s.HashedPassword, &s.Burn, &s.Created, &s.Modified, nullTime{&s.Expires},
+		&s.Stars}
+}
+
+// fullDest is like dest(), for a row of snippetColumns followed by
+// snippetContentColumn.
+func (s *Snippet) fullDest() []any {
+	return append(s.dest(), gzipContent{&s.Content})
+}
+
+// nullTime scans a nullable DATETIME column into a time.Time, leaving it as
+// the zero time for NULL.
+type nullTime struct {
+	t *time.Time
+}
+
+func (n nullTime) Scan(value any) error {
+	var nt sql.NullTime
+
+	err := nt.Scan(value)
+	if err != nil {
+		return err
+	}
+
+	*n.t = nt.Time
+	return nil
+}
+
+// SnippetPageSize is the number of snippets on each page returned by List().
+const SnippetPageSize = 20
+
+// pastLastPage reports whether page comes after the last page of total
+// snippets. The paginated queries count first and skip fetching such a page,
+// so that a page number far past the end never reaches the OFFSET. The first
+// page always exists, even when it's empty.
+func pastLastPage(page, total int) bool {
+	return page > 1 && page-1 >= (total+SnippetPageSize-1)/SnippetPageSize
+}
+
+// SnippetFilter narrows down the snippets returned by List(). The zero value
+// matches every live snippet.
+type SnippetFilter struct {
+	UserID int    // only snippets created by this user, if non-zero
+	Tag    string // only snippets with this tag, if not empty
 }
 
 // Define a SnippetModel type which wraps a sql.DB connection pool.
 type SnippetModel struct {
 	DB *sql.DB
+	// CompressOver is the size in bytes above which content is stored
+	// gzipped, or 0 to store everything as it is.
+	CompressOver int
 }
 
 type SnippetModelInterface interface {
-	Insert(title string, content string, expires int) (int, error)
-	Get(id int) (*Snippet, error)
+	Insert(userID int, in SnippetInput) (int, string, error)
+	Get(id int, viewerID int) (*Snippet, error)
+	GetByPublicID(publicID string, viewerID int) (*Snippet, error)
+	Burn(id int, viewerID int) (*Snippet, error)
 	Latest() ([]*Snippet, error)
+	Forks(id int) ([]*Snippet, error)
+	List(filter SnippetFilter, page int) ([]*Snippet, int, error)
+	Search(query SearchQuery, page int) ([]*Snippet, int, error)
+	Update(id int, in SnippetInput) error
+	Delete(id int) error
+	Expire(id int) error
+	Restore(id int) error
+	Deleted() ([]*Snippet, error)
+	Revisions(snippetID int) ([]*Revision, error)
+	Revision(snippetID int, id int) (*Revision, error)
+	TagCounts(limit int) ([]*Tag, error)
+	Star(snippetID, userID int) error
+	Unstar(snippetID, userID int) error
+	Starred(snippetID, userID int) (bool, error)
+	Stars(userID int, page int) ([]*Snippet, int, error)
+	MostStarred(since time.Time, limit int) ([]*Snippet, error)
+	StarCounts(userID int) (received int, given int, err error)
+	DailyViews(snippetID int, since time.Time) ([]*DailyViews, error)
 }
 
-// This will return a specific snippet based on its id.
-func (m *SnippetModel) Get(id int) (*Snippet, error) {
+// This will return a specific snippet based on its id. Private snippets are
+// only returned when viewerID is their author; pass 0 for an anonymous viewer.
+func (m *SnippetModel) Get(id int, viewerID int) (*Snippet, error) {
 	// Write the SQL statement we want to execute. Again, I've split it over two
 	// lines for readability.
-	stmt := `SELECT id, title, content, created, expires FROM snippets
-    WHERE expires > UTC_TIMESTAMP() AND id = ?`
+	// The author's name lives in the users table, so we join on user_id to
+	// fetch it alongside the snippet.
+	stmt := `SELECT ` + snippetColumns + snippetContentColumn + `
+    FROM snippets s INNER JOIN users u ON u.id = s.user_id
+    WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP()) AND s.deleted IS NULL AND s.id = ?
+    AND (s.visibility <> 'private' OR s.user_id = ?)`
 
 	// Use the QueryRow() method on the connection pool to execute our
 	// SQL statement, passing in the untrusted id variable as the value for the
 	// placeholder parameter. This returns a pointer to a sql.Row object which
 	// holds the result from the database.
-	row := m.DB.QueryRow(stmt, id)
+	row := m.DB.QueryRow(stmt, id, viewerID)
 
 	// Initialize a pointer to a new zeroed Snippet struct.
 	s := &Snippet{}
@@ -49,7 +257,7 @@ func (m *SnippetModel) Get(id int) (*Snippet, error) {
 	// to row.Scan are *pointers* to the place you want to copy the data into,
 	// and the number of arguments must be exactly the same as the number of
 	// columns returned by your statement.
-	err := row.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+	err := row.Scan(s.fullDest()...)
 	if err != nil {
 		// If the query returns no rows, then row.Scan() will return a
 		// sql.ErrNoRows error. We use the errors.Is() function check for that
@@ -62,47 +270,233 @@ func (m *SnippetModel) Get(id int) (*Snippet, error) {
 		}
 	}
 
+	s.Tags, err = m.snippetTags(s.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Files, err = snippetFiles(m.DB, s.ID)
+	if err != nil {
+		return nil, err
+	}
+
 	// If everything went OK then return the Snippet object.
 	return s, nil
 }
 
-// This will insert a new snippet into the database.
-func (m *SnippetModel) Insert(title string, content string, expires int) (int, error) {
+// This will read a burn-after-reading snippet and delete it for good, in a
+// single transaction. The row is locked while it's read, so if two people ask
+// for the snippet at the same time only one of them gets it; the other gets
+// ErrNoRecord, as does anyone asking for a snippet that isn't a burn one.
+// Unlike Get(), the snippet's Tags are not filled in.
+func (m *SnippetModel) Burn(id int, viewerID int) (*Snippet, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt := `SELECT ` + snippetColumns + snippetContentColumn + `
+    FROM snippets s INNER JOIN users u ON u.id = s.user_id
+    WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP()) AND s.deleted IS NULL AND s.burn AND s.id = ?
+    AND (s.visibility <> 'private' OR s.user_id = ?)
+    FOR UPDATE`
+
+	s := &Snippet{}
+
+	err = tx.QueryRow(stmt, id, viewerID).Scan(s.fullDest()...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	s.Files, err = snippetFiles(tx, s.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Revisions, tags, files, comments and stars go with it, thanks to ON DELETE CASCADE.
+	_, err = tx.Exec(`DELETE FROM snippets WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// This will insert a new snippet into the database, owned by the user with the
+// given ID, together with its files, and return its ID and public ID. The
+// public ID is random, and in the unlikely event that it's already taken
+// another one is tried.
+func (m *SnippetModel) Insert(userID int, in SnippetInput) (int, string, error) {
+	hashedPassword, err := in.hashedPassword()
+	if err != nil {
+		return 0, "", err
+	}
+
+	content, contentGz, err := compressContent(in.Content, m.CompressOver)
+	if err != nil {
+		return 0, "", err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, "", err
+	}
+	defer tx.Rollback()
+
 	// Write the SQL statement we want to execute. I've split it over two lines
 	// for readability (which is why it's surrounded with backquotes instead
 	// of normal double quotes).
-	stmt := `INSERT INTO snippets (title, content, created, expires)
-    VALUES(?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))`
+	stmt := `INSERT INTO snippets (public_id, user_id, parent_id, title, filename, content, content_gz, language, format, visibility, hashed_password, burn, created, modified, expires)
+    VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, UTC_TIMESTAMP(), UTC_TIMESTAMP(), ?)`
 
-	// Use the Exec() method on the embedded connection pool to execute the
-	// statement. The first parameter is the SQL statement, followed by the
-	// title, content and expiry values for the placeholder parameters. This
-	// method returns a sql.Result type, which contains some basic
-	// information about what happened when the statement was executed.
-	result, err := m.DB.Exec(stmt, title, content, expires)
-	if err != nil {
-		return 0, err
+	var result sql.Result
+	var publicID string
+
+	for attempt := 0; ; attempt++ {
+		publicID, err = newPublicID()
+		if err != nil {
+			return 0, "", err
+		}
+
+		// Use the Exec() method on the embedded connection pool to execute the
+		// statement. The first parameter is the SQL statement, followed by the
+		// title, content and expiry values for the placeholder parameters. This
+		// method returns a sql.Result type, which contains some basic
+		// information about what happened when the statement was executed.
+		result, err = tx.Exec(stmt, publicID, userID, in.parentID(), in.Title, in.Filename, content, contentGz, in.Language, in.Format, in.Visibility, hashedPassword, in.Burn, in.expires())
+		if err == nil {
+			break
+		}
+		if !isDuplicatePublicID(err) || attempt == 2 {
+			return 0, "", err
+		}
 	}
 
 	// Use the LastInsertId() method on the result to get the ID of our
 	// newly inserted record in the snippets table.
 	id, err := result.LastInsertId()
 	if err != nil {
-		return 0, err
+		return 0, "", err
+	}
+
+	err = setFiles(tx, int(id), in.Files, m.CompressOver)
+	if err != nil {
+		return 0, "", err
+	}
+
+	err = setTags(tx, int(id), in.Tags)
+	if err != nil {
+		return 0, "", err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, "", err
 	}
 
 	// The ID returned has the type int64, so we convert it to an int type
 	// before returning.
-	return int(id), nil
+	return int(id), publicID, nil
 
 }
 
-// This will return the 10 most recently created snippets.
+// This will overwrite the title, content and files of an existing snippet,
+// bump its modified timestamp and set a new expiry. The old title and every
+// old file are saved as a revision first, in the same transaction, so that no
+// edit is ever lost. Checking that the caller is allowed to do this is left
+// to the handler.
+func (m *SnippetModel) Update(id int, in SnippetInput) error {
+	// Hash the password before starting the transaction, as it's slow.
+	hashedPassword, err := in.hashedPassword()
+	if err != nil {
+		return err
+	}
+	keepPassword := hashedPassword == nil && !in.ClearPassword
+
+	content, contentGz, err := compressContent(in.Content, m.CompressOver)
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	// Rollback is a no-op once the transaction has been committed.
+	defer tx.Rollback()
+
+	stmt := `INSERT INTO snippet_revisions (snippet_id, title, filename, content, content_gz, created)
+    SELECT id, title, filename, content, content_gz, modified FROM snippets
+    WHERE (expires IS NULL OR expires > UTC_TIMESTAMP()) AND deleted IS NULL AND id = ?`
+
+	result, err := tx.Exec(stmt, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNoRecord
+	}
+
+	revisionID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	stmt = `INSERT INTO snippet_revision_files (revision_id, position, name, language, content, content_gz)
+    SELECT ?, position, name, language, content, content_gz FROM snippet_files WHERE snippet_id = ?`
+
+	_, err = tx.Exec(stmt, revisionID, id)
+	if err != nil {
+		return err
+	}
+
+	stmt = `UPDATE snippets SET title = ?, filename = ?, content = ?, content_gz = ?, language = ?, format = ?, visibility = ?,
+    hashed_password = IF(?, hashed_password, ?), burn = IF(?, burn, ?), modified = UTC_TIMESTAMP(),
+    expires = IF(?, expires, ?)
+    WHERE id = ?`
+
+	_, err = tx.Exec(stmt, in.Title, in.Filename, content, contentGz, in.Language, in.Format, in.Visibility, keepPassword, hashedPassword, in.KeepExpiry, in.Burn, in.KeepExpiry, in.expires(), id)
+	if err != nil {
+		return err
+	}
+
+	err = setFiles(tx, id, in.Files, m.CompressOver)
+	if err != nil {
+		return err
+	}
+
+	err = setTags(tx, id, in.Tags)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// This will return the 10 most recently created public snippets. Like every
+// other listing, it leaves out burn-after-reading snippets: the first visitor
+// or crawler to follow a link to one would destroy it.
 func (m *SnippetModel) Latest() ([]*Snippet, error) {
 
 	// Write the SQL statement we want to execute.
-	stmt := `SELECT id, title, content, created, expires FROM snippets
-    WHERE expires > UTC_TIMESTAMP() ORDER BY id DESC LIMIT 10`
+	stmt := `SELECT ` + snippetColumns + `
+    FROM snippets s INNER JOIN users u ON u.id = s.user_id
+    WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP()) AND s.deleted IS NULL AND s.visibility = 'public' AND NOT s.burn
+    ORDER BY s.id DESC LIMIT 10`
 
 	// Use the Query() method on the connection pool to execute our
 	// SQL statement. This returns a sql.Rows resultset containing the result of
@@ -135,7 +529,7 @@ func (m *SnippetModel) Latest() ([]*Snippet, error) {
 		// must be pointers to the place you want to copy the data into, and the
 		// number of arguments must be exactly the same as the number of
 		// columns returned by your statement.
-		err = rows.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+		err = rows.Scan(s.dest()...)
 		if err != nil {
 			return nil, err
 		}
@@ -154,3 +548,241 @@ func (m *SnippetModel) Latest() ([]*Snippet, error) {
 	// If everything went OK then return the Snippets slice.
 	return snippets, nil
 }
+
+// This will return the live public snippets forked from the given one, oldest
+// first.
+func (m *SnippetModel) Forks(id int) ([]*Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + `
+    FROM snippets s INNER JOIN users u ON u.id = s.user_id
+    WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP()) AND s.deleted IS NULL AND s.visibility = 'public' AND NOT s.burn
+    AND s.parent_id = ? ORDER BY s.id`
+
+	return m.query(stmt, id)
+}
+
+// This will return one page of the live public snippets matching the filter,
+// other than burn-after-reading ones, newest first, along with the total number of matching snippets so that the
+// caller can work out how many pages there are. Pages are numbered from 1.
+func (m *SnippetModel) List(filter SnippetFilter, page int) ([]*Snippet, int, error) {
+	// Build up the WHERE clause and its arguments from the filter. Only fixed
+	// SQL fragments are ever added to it; user input goes in args.
+	where := "(s.expires IS NULL OR s.expires > UTC_TIMESTAMP()) AND s.deleted IS NULL AND s.visibility = 'public' AND NOT s.burn"
+	args := []any{}
+
+	if filter.UserID != 0 {
+		where += " AND s.user_id = ?"
+		args = append(args, filter.UserID)
+	}
+
+	if filter.Tag != "" {
+		where += ` AND s.id IN (SELECT st.snippet_id FROM snippet_tags st
+        INNER JOIN tags t ON t.id = st.tag_id WHERE t.name = ?)`
+		args = append(args, filter.Tag)
+	}
+
+	var total int
+
+	stmt := "SELECT COUNT(*) FROM snippets s WHERE " + where
+
+	err := m.DB.QueryRow(stmt, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if pastLastPage(page, total) {
+		return []*Snippet{}, total, nil
+	}
+
+	stmt = `SELECT ` + snippetColumns + `
+    FROM snippets s INNER JOIN users u ON u.id = s.user_id
+    WHERE ` + where + ` ORDER BY s.id DESC LIMIT ? OFFSET ?`
+
+	args = append(args, SnippetPageSize, (page-1)*SnippetPageSize)
+
+	snippets, err := m.query(stmt, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return snippets, total, nil
+}
+
+// This will return one page of the live public snippets matching a full-text
+// search, best matches first, along with the total number of matches.
+// Password-protected and burn-after-reading snippets are left out, as the
+// results show part of what they contain.
+func (m *SnippetModel) Search(query SearchQuery, page int) ([]*Snippet, int, error) {
+	// A boolean-mode search with no required terms would match nothing, so
+	// don't bother asking the database.
+	if len(query.Terms) == 0 {
+		return []*Snippet{}, 0, nil
+	}
+
+	against := query.BooleanMode()
+
+	var total int
+
+	stmt := `SELECT COUNT(*) FROM snippets s
+    WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP()) AND s.deleted IS NULL AND s.visibility = 'public'
+    AND s.hashed_password IS NULL AND NOT s.burn AND MATCH(s.title, s.content) AGAINST(? IN BOOLEAN MODE)`
+
+	err := m.DB.QueryRow(stmt, against).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if pastLastPage(page, total) {
+		return []*Snippet{}, total, nil
+	}
+
+	stmt = `SELECT ` + snippetColumns + `
+    FROM snippets s INNER JOIN users u ON u.id = s.user_id
+    WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP()) AND s.deleted IS NULL AND s.visibility = 'public'
+    AND s.hashed_password IS NULL AND NOT s.burn AND MATCH(s.title, s.content) AGAINST(? IN BOOLEAN MODE)
+    ORDER BY MATCH(s.title, s.content) AGAINST(? IN BOOLEAN MODE) DESC, s.id DESC
+    LIMIT ? OFFSET ?`
+
+	snippets, err := m.query(stmt, against, against, SnippetPageSize, (page-1)*SnippetPageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return snippets, total, nil
+}
+
+// This will soft-delete a snippet by stamping its deleted column. The row
+// stays in the table so that an admin can bring it back with Restore().
+func (m *SnippetModel) Delete(id int) error {
+	stmt := `UPDATE snippets SET deleted = UTC_TIMESTAMP()
+    WHERE deleted IS NULL AND id = ?`
+
+	return m.execOne(stmt, id)
+}
+
+// This will expire a snippet immediately, exactly as if its expiry date had
+// just passed.
+func (m *SnippetModel) Expire(id int) error {
+	stmt := `UPDATE snippets SET expires = UTC_TIMESTAMP()
+    WHERE (expires IS NULL OR expires > UTC_TIMESTAMP()) AND deleted IS NULL AND id = ?`
+
+	return m.execOne(stmt, id)
+}
+
+// This will permanently delete up to limit snippets that expired at or before
+// the given time, oldest first, and return their IDs. Their revisions and tags
+// go with them. Snippets that never expire are left alone.
+func (m *SnippetModel) PurgeExpired(before time.Time, limit int) ([]int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt := `SELECT id FROM snippets WHERE expires IS NOT NULL AND expires <= ?
+    ORDER BY expires, id LIMIT ? FOR UPDATE`
+
+	rows, err := tx.Query(stmt, before.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	args := []any{}
+
+	for rows.Next() {
+		var id int
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+		args = append(args, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	stmt = `DELETE FROM snippets WHERE id IN (?` + strings.Repeat(", ?", len(ids)-1) + `)`
+
+	_, err = tx.Exec(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// This will undo a soft delete made by Delete().
+func (m *SnippetModel) Restore(id int) error {
+	stmt := `UPDATE snippets SET deleted = NULL
+    WHERE deleted IS NOT NULL AND id = ?`
+
+	return m.execOne(stmt, id)
+}
+
+// This will return every soft-deleted snippet, most recently deleted first,
+// so that an admin can decide which ones to restore.
+func (m *SnippetModel) Deleted() ([]*Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + `
+    FROM snippets s INNER JOIN users u ON u.id = s.user_id
+    WHERE s.deleted IS NOT NULL ORDER BY s.deleted DESC`
+
+	return m.query(stmt)
+}
+
+// query runs a statement selecting the usual snippet columns (see Get) and
+// scans every row into a Snippet.
+func (m *SnippetModel) query(stmt string, args ...any) ([]*Snippet, error) {
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snippets := []*Snippet{}
+
+	for rows.Next() {
+		s := &Snippet{}
+		err = rows.Scan(s.dest()...)
+		if err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return snippets, nil
+}
+
+// execOne runs a statement that is expected to change exactly one snippet and
+// returns ErrNoRecord if it didn't change any.
+func (m *SnippetModel) execOne(stmt string, args ...any) error {
+	result, err := m.DB.Exec(stmt, args...)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/Baytancha/snip56/internal/assert"
+)
+
+func TestPastLastPage(t *testing.T) {
+	assert.Equal(t, pastLastPage(1, 0), false)
+	assert.Equal(t, pastLastPage(2, 0), true)
+	assert.Equal(t, pastLastPage(1, SnippetPageSize), false)
+	assert.Equal(t, pastLastPage(2, SnippetPageSize), true)
+	assert.Equal(t, pastLastPage(2, SnippetPageSize+1), false)
+	assert.Equal(t, pastLastPage(3, SnippetPageSize+1), true)
+}
+
+func TestSnippetModelListsSkipBurn(t *testing.T) {
+	db := newTestDB(t)
+	m := SnippetModel{DB: db}
+
+	// A public burn-after-reading snippet would be destroyed by the first
+	// visitor or crawler to follow a link to it, so it's never listed.
+	in := SnippetInput{
+		Title:      "An old silent pond",
+		Content:    "An old silent pond...",
+		Language:   "plaintext",
+		Format:     FormatPlain,
+		Visibility: VisibilityPublic,
+		Tags:       []string{"haiku"},
+	}
+	keptID, _, err := m.Insert(1, in)
+	assert.NilError(t, err)
+
+	in.Title = "Over the wintry forest"
+	in.Burn = true
+	burnID, _, err := m.Insert(1, in)
+	assert.NilError(t, err)
+
+	latest, err := m.Latest()
+	assert.NilError(t, err)
+	assert.Equal(t, len(latest), 1)
+	assert.Equal(t, latest[0].ID, keptID)
+
+	for _, filter := range []SnippetFilter{{}, {UserID: 1}, {Tag: "haiku"}} {
+		snippets, total, err := m.List(filter, 1)
+		assert.NilError(t, err)
+		assert.Equal(t, total, 1)
+		assert.Equal(t, len(snippets), 1)
+		assert.Equal(t, snippets[0].ID, keptID)
+	}
+
+	// It can still be read, once.
+	s, err := m.Get(burnID, 0)
+	assert.NilError(t, err)
+	assert.Equal(t, s.Burn, true)
+}
+
+func TestSnippetModelUpdateKeepsFiles(t *testing.T) {
+	db := newTestDB(t)
+	m := SnippetModel{DB: db}
+
+	in := SnippetInput{
+		Title:      "Snippetbox in Docker",
+		Filename:   "Dockerfile",
+		Content:    "FROM golang:1.21\n",
+		Language:   "dockerfile",
+		Format:     FormatCode,
+		Visibility: VisibilityPublic,
+		Files:      []File{{Name: "compose.yaml", Language: "yaml", Content: "services:\n  app:\n"}},
+	}
+	id, _, err := m.Insert(1, in)
+	assert.NilError(t, err)
+
+	in.Files = []File{{Name: "compose.yaml", Language: "yaml", Content: "services:\n  web:\n"}}
+	err = m.Update(id, in)
+	assert.NilError(t, err)
+
+	revisions, err := m.Revisions(id)
+	assert.NilError(t, err)
+	assert.Equal(t, len(revisions), 1)
+
+	// The revision has the old version of every file, not just the main one.
+	r, err := m.Revision(id, revisions[0].ID)
+	assert.NilError(t, err)
+	assert.Equal(t, r.Filename, "Dockerfile")
+	assert.Equal(t, len(r.Files), 1)
+	assert.Equal(t, r.Files[0].Content, "services:\n  app:\n")
+}
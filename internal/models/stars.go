@@ -0,0 +1,91 @@
+package models
+
+import (
+	"time"
+)
+
+// This will star a snippet on behalf of a user. Starring a snippet twice has
+// no effect.
+func (m *SnippetModel) Star(snippetID, userID int) error {
+	stmt := `INSERT IGNORE INTO stars (snippet_id, user_id, created)
+    VALUES (?, ?, UTC_TIMESTAMP())`
+
+	_, err := m.DB.Exec(stmt, snippetID, userID)
+	return err
+}
+
+// This will remove a user's star from a snippet, if they had starred it.
+func (m *SnippetModel) Unstar(snippetID, userID int) error {
+	_, err := m.DB.Exec("DELETE FROM stars WHERE snippet_id = ? AND user_id = ?", snippetID, userID)
+	return err
+}
+
+// This will report whether a user has starred a snippet.
+func (m *SnippetModel) Starred(snippetID, userID int) (bool, error) {
+	var exists bool
+
+	stmt := "SELECT EXISTS(SELECT true FROM stars WHERE snippet_id = ? AND user_id = ?)"
+
+	err := m.DB.QueryRow(stmt, snippetID, userID).Scan(&exists)
+	return exists, err
+}
+
+// This will return one page of the live snippets a user has starred, most
+// recently starred first, along with the total number of them. Snippets that
+// have since been made private by someone else are left out.
+func (m *SnippetModel) Stars(userID int, page int) ([]*Snippet, int, error) {
+	where := `st.user_id = ? AND (s.expires IS NULL OR s.expires > UTC_TIMESTAMP()) AND s.deleted IS NULL
+    AND (s.visibility <> 'private' OR s.user_id = st.user_id) AND NOT s.burn`
+
+	var total int
+
+	stmt := `SELECT COUNT(*) FROM stars st INNER JOIN snippets s ON s.id = st.snippet_id WHERE ` + where
+
+	err := m.DB.QueryRow(stmt, userID).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if pastLastPage(page, total) {
+		return []*Snippet{}, total, nil
+	}
+
+	stmt = `SELECT ` + snippetColumns + `
+    FROM stars st INNER JOIN snippets s ON s.id = st.snippet_id INNER JOIN users u ON u.id = s.user_id
+    WHERE ` + where + ` ORDER BY st.created DESC, s.id DESC LIMIT ? OFFSET ?`
+
+	snippets, err := m.query(stmt, userID, SnippetPageSize, (page-1)*SnippetPageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return snippets, total, nil
+}
+
+// This will return how many stars a user's live public snippets have received
+// and how many live public snippets the user has starred. Only public
+// snippets are counted, as the numbers are shown on their profile.
+func (m *SnippetModel) StarCounts(userID int) (received int, given int, err error) {
+	stmt := `SELECT
+    (SELECT COUNT(*) FROM stars st INNER JOIN snippets s ON s.id = st.snippet_id
+        WHERE s.user_id = ? AND (s.expires IS NULL OR s.expires > UTC_TIMESTAMP()) AND s.deleted IS NULL AND s.visibility = 'public' AND NOT s.burn),
+    (SELECT COUNT(*) FROM stars st INNER JOIN snippets s ON s.id = st.snippet_id
+        WHERE st.user_id = ? AND (s.expires IS NULL OR s.expires > UTC_TIMESTAMP()) AND s.deleted IS NULL AND s.visibility = 'public' AND NOT s.burn)`
+
+	err = m.DB.QueryRow(stmt, userID, userID).Scan(&received, &given)
+	return received, given, err
+}
+
+// This will return up to limit live public snippets which have been starred
+// since the given time, those with the most new stars first.
+func (m *SnippetModel) MostStarred(since time.Time, limit int) ([]*Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + `
+    FROM snippets s INNER JOIN users u ON u.id = s.user_id
+    INNER JOIN (
+        SELECT snippet_id, COUNT(*) AS recent FROM stars WHERE created >= ? GROUP BY snippet_id
+    ) r ON r.snippet_id = s.id
+    WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP()) AND s.deleted IS NULL AND s.visibility = 'public' AND NOT s.burn
+    ORDER BY r.recent DESC, s.id DESC LIMIT ?`
+
+	return m.query(stmt, since.UTC(), limit)
+}
@@ -0,0 +1,105 @@
+package models
+
+import "database/sql"
+
+// Define a Tag type to hold a tag name together with the number of live
+// snippets that carry it.
+type Tag struct {
+	Name  string
+	Count int
+}
+
+// setTags replaces the tags on a snippet with the given ones, as part of
+// Insert() or Update(). Tags that haven't been used before are created on the
+// fly. The names are expected to have been validated already.
+func setTags(tx *sql.Tx, snippetID int, tags []string) error {
+	_, err := tx.Exec("DELETE FROM snippet_tags WHERE snippet_id = ?", snippetID)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range tags {
+		// INSERT IGNORE skips names which already exist, thanks to the
+		// unique constraint on tags.name.
+		_, err = tx.Exec("INSERT IGNORE INTO tags (name) VALUES (?)", name)
+		if err != nil {
+			return err
+		}
+
+		stmt := `INSERT IGNORE INTO snippet_tags (snippet_id, tag_id)
+    SELECT ?, id FROM tags WHERE name = ?`
+
+		_, err = tx.Exec(stmt, snippetID, name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// This will return the most used tags across live public snippets, ordered by
+// name, for use in a tag cloud.
+func (m *SnippetModel) TagCounts(limit int) ([]*Tag, error) {
+	stmt := `SELECT name, count FROM (
+        SELECT t.name, COUNT(*) AS count FROM tags t
+        INNER JOIN snippet_tags st ON st.tag_id = t.id
+        INNER JOIN snippets s ON s.id = st.snippet_id
+        WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP()) AND s.deleted IS NULL AND s.visibility = 'public' AND NOT s.burn
+        GROUP BY t.id, t.name ORDER BY count DESC, t.name LIMIT ?
+    ) top ORDER BY name`
+
+	rows, err := m.DB.Query(stmt, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []*Tag{}
+
+	for rows.Next() {
+		t := &Tag{}
+		err = rows.Scan(&t.Name, &t.Count)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// snippetTags returns the names of the tags on a snippet, in alphabetical
+// order.
+func (m *SnippetModel) snippetTags(snippetID int) ([]string, error) {
+	stmt := `SELECT t.name FROM tags t
+    INNER JOIN snippet_tags st ON st.tag_id = t.id
+    WHERE st.snippet_id = ? ORDER BY t.name`
+
+	rows, err := m.DB.Query(stmt, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []string{}
+
+	for rows.Next() {
+		var name string
+		err = rows.Scan(&name)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, name)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,175 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Define a Template type to hold a starting point for new snippets. A template
+// belongs to the user who saved it, or to nobody if an admin made it available
+// to everyone.
+type Template struct {
+	ID            int
+	UserID        int // 0 for a site-wide template
+	Name          string
+	Title         string
+	Content       string
+	Language      string
+	Format        string
+	Expires       string // one of the expiry choices on the snippet form
+	ExpiresAmount int    // for a custom expiry
+	ExpiresUnit   string // "hours" or "days", for a custom expiry
+	Created       time.Time
+	Modified      time.Time
+}
+
+// SiteWide reports whether the template is offered to every user.
+func (t *Template) SiteWide() bool {
+	return t.UserID == 0
+}
+
+// TemplateInput holds the user-editable fields of a template, as passed to
+// Insert() and Update().
+type TemplateInput struct {
+	Name          string
+	Title         string
+	Content       string
+	Language      string
+	Format        string
+	Expires       string
+	ExpiresAmount int
+	ExpiresUnit   string
+}
+
+type TemplateModelInterface interface {
+	Insert(userID int, in TemplateInput) (int, error)
+	Get(id int, viewerID int) (*Template, error)
+	Update(id int, in TemplateInput) error
+	Delete(id int) error
+	ForUser(userID int) ([]*Template, error)
+}
+
+// Define a TemplateModel type which wraps a sql.DB connection pool.
+type TemplateModel struct {
+	DB *sql.DB
+}
+
+// templateColumns are the columns selected by queries returning whole
+// templates, in the order expected by Template.dest().
+const templateColumns = `id, COALESCE(user_id, 0), name, title, content, language, format,
+    expires, expires_amount, expires_unit, created, modified`
+
+func (t *Template) dest() []any {
+	return []any{&t.ID, &t.UserID, &t.Name, &t.Title, &t.Content, &t.Language, &t.Format,
+		&t.Expires, &t.ExpiresAmount, &t.ExpiresUnit, &t.Created, &t.Modified}
+}
+
+// ownerID returns the value stored in the user_id column: NULL for site-wide
+// templates.
+func ownerID(userID int) any {
+	if userID == 0 {
+		return nil
+	}
+	return userID
+}
+
+// This will save a new template owned by the given user, or a site-wide one
+// if userID is 0.
+func (m *TemplateModel) Insert(userID int, in TemplateInput) (int, error) {
+	stmt := `INSERT INTO templates (user_id, name, title, content, language, format, expires, expires_amount, expires_unit, created, modified)
+    VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, UTC_TIMESTAMP(), UTC_TIMESTAMP())`
+
+	result, err := m.DB.Exec(stmt, ownerID(userID), in.Name, in.Title, in.Content, in.Language, in.Format,
+		in.Expires, in.ExpiresAmount, in.ExpiresUnit)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// This will return a template if viewerID may use it: site-wide templates are
+// returned to anyone, and others only to their owner.
+func (m *TemplateModel) Get(id int, viewerID int) (*Template, error) {
+	stmt := `SELECT ` + templateColumns + ` FROM templates
+    WHERE id = ? AND (user_id IS NULL OR user_id = ?)`
+
+	t := &Template{}
+
+	err := m.DB.QueryRow(stmt, id, viewerID).Scan(t.dest()...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// This will replace the fields of a template. Its owner doesn't change.
+func (m *TemplateModel) Update(id int, in TemplateInput) error {
+	stmt := `UPDATE templates SET name = ?, title = ?, content = ?, language = ?, format = ?,
+    expires = ?, expires_amount = ?, expires_unit = ?, modified = UTC_TIMESTAMP()
+    WHERE id = ?`
+
+	_, err := m.DB.Exec(stmt, in.Name, in.Title, in.Content, in.Language, in.Format,
+		in.Expires, in.ExpiresAmount, in.ExpiresUnit, id)
+	return err
+}
+
+// This will delete a template. Snippets created from it are left alone.
+func (m *TemplateModel) Delete(id int) error {
+	result, err := m.DB.Exec("DELETE FROM templates WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// This will return the templates a user can choose from: the site-wide ones
+// first, then their own, each in alphabetical order. Pass 0 to get just the
+// site-wide templates.
+func (m *TemplateModel) ForUser(userID int) ([]*Template, error) {
+	stmt := `SELECT ` + templateColumns + ` FROM templates
+    WHERE user_id IS NULL OR user_id = ?
+    ORDER BY user_id IS NOT NULL, name, id`
+
+	rows, err := m.DB.Query(stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []*Template{}
+
+	for rows.Next() {
+		t := &Template{}
+		err = rows.Scan(t.dest()...)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return templates, nil
+}
@@ -1,26 +1,177 @@
+CREATE TABLE users (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    hashed_password CHAR(60) NOT NULL,
+    created DATETIME NOT NULL,
+    admin BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+ALTER TABLE users ADD CONSTRAINT users_uc_email UNIQUE (email);
+
 CREATE TABLE snippets (
     id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    public_id CHAR(8) CHARACTER SET ascii COLLATE ascii_bin NOT NULL,
+    user_id INTEGER NOT NULL,
+    parent_id INTEGER NULL,
     title VARCHAR(100) NOT NULL,
-    content TEXT NOT NULL,
+    filename VARCHAR(100) NOT NULL DEFAULT '',
+    content MEDIUMTEXT NOT NULL,
+    content_gz MEDIUMBLOB NULL,
+    language VARCHAR(30) NOT NULL DEFAULT '',
+    format ENUM('plain', 'code', 'markdown') NOT NULL DEFAULT 'code',
+    visibility ENUM('public', 'unlisted', 'private') NOT NULL DEFAULT 'public',
+    hashed_password CHAR(60) NULL,
+    burn BOOLEAN NOT NULL DEFAULT FALSE,
     created DATETIME NOT NULL,
-    expires DATETIME NOT NULL
+    modified DATETIME NOT NULL,
+    expires DATETIME NULL,
+    deleted DATETIME NULL
 );
 
 CREATE INDEX idx_snippets_created ON snippets(created);
+CREATE INDEX idx_snippets_expires ON snippets(expires);
+ALTER TABLE snippets ADD CONSTRAINT snippets_uc_public_id UNIQUE (public_id);
 
-CREATE TABLE users (
+CREATE FULLTEXT INDEX idx_snippets_fulltext ON snippets(title, content);
+
+ALTER TABLE snippets ADD CONSTRAINT snippets_fk_user_id FOREIGN KEY (user_id) REFERENCES users(id);
+
+ALTER TABLE snippets ADD CONSTRAINT snippets_fk_parent_id FOREIGN KEY (parent_id) REFERENCES snippets(id) ON DELETE SET NULL;
+
+CREATE TABLE snippet_revisions (
     id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
-    name VARCHAR(255) NOT NULL,
-    email VARCHAR(255) NOT NULL,
-    hashed_password CHAR(60) NOT NULL,
+    snippet_id INTEGER NOT NULL,
+    title VARCHAR(100) NOT NULL,
+    filename VARCHAR(100) NOT NULL DEFAULT '',
+    content MEDIUMTEXT NOT NULL,
+    content_gz MEDIUMBLOB NULL,
     created DATETIME NOT NULL
 );
 
-ALTER TABLE users ADD CONSTRAINT users_uc_email UNIQUE (email);
+ALTER TABLE snippet_revisions ADD CONSTRAINT snippet_revisions_fk_snippet_id FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE;
+
+CREATE TABLE snippet_files (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    snippet_id INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    language VARCHAR(30) NOT NULL DEFAULT '',
+    content MEDIUMTEXT NOT NULL,
+    content_gz MEDIUMBLOB NULL
+);
+
+ALTER TABLE snippet_files ADD CONSTRAINT snippet_files_uc_name UNIQUE (snippet_id, name);
+ALTER TABLE snippet_files ADD CONSTRAINT snippet_files_fk_snippet_id FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE;
+
+CREATE TABLE snippet_revision_files (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    revision_id INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    language VARCHAR(30) NOT NULL DEFAULT '',
+    content MEDIUMTEXT NOT NULL,
+    content_gz MEDIUMBLOB NULL
+);
+
+ALTER TABLE snippet_revision_files ADD CONSTRAINT snippet_revision_files_uc_name UNIQUE (revision_id, name);
+ALTER TABLE snippet_revision_files ADD CONSTRAINT snippet_revision_files_fk_revision_id FOREIGN KEY (revision_id) REFERENCES snippet_revisions(id) ON DELETE CASCADE;
+
+CREATE TABLE comments (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    snippet_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    parent_id INTEGER NULL,
+    body TEXT NOT NULL,
+    created DATETIME NOT NULL,
+    deleted DATETIME NULL
+);
+
+CREATE INDEX idx_comments_snippet_id ON comments(snippet_id, created);
+
+ALTER TABLE comments ADD CONSTRAINT comments_fk_snippet_id FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE;
+ALTER TABLE comments ADD CONSTRAINT comments_fk_user_id FOREIGN KEY (user_id) REFERENCES users(id);
+ALTER TABLE comments ADD CONSTRAINT comments_fk_parent_id FOREIGN KEY (parent_id) REFERENCES comments(id) ON DELETE CASCADE;
+
+CREATE TABLE stars (
+    user_id INTEGER NOT NULL,
+    snippet_id INTEGER NOT NULL,
+    created DATETIME NOT NULL,
+    PRIMARY KEY (user_id, snippet_id)
+);
+
+CREATE INDEX idx_stars_snippet_id ON stars(snippet_id, created);
+
+ALTER TABLE stars ADD CONSTRAINT stars_fk_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE stars ADD CONSTRAINT stars_fk_snippet_id FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE;
+
+CREATE TABLE snippet_views (
+    snippet_id INTEGER NOT NULL,
+    day DATE NOT NULL,
+    views INTEGER NOT NULL,
+    PRIMARY KEY (snippet_id, day)
+);
+
+ALTER TABLE snippet_views ADD CONSTRAINT snippet_views_fk_snippet_id FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE;
+
+CREATE TABLE collections (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    user_id INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL,
+    visibility ENUM('public', 'unlisted', 'private') NOT NULL DEFAULT 'public',
+    created DATETIME NOT NULL,
+    modified DATETIME NOT NULL
+);
+
+ALTER TABLE collections ADD CONSTRAINT collections_fk_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE TABLE collection_snippets (
+    collection_id INTEGER NOT NULL,
+    snippet_id INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (collection_id, snippet_id)
+);
+
+ALTER TABLE collection_snippets ADD CONSTRAINT collection_snippets_fk_collection_id FOREIGN KEY (collection_id) REFERENCES collections(id) ON DELETE CASCADE;
+ALTER TABLE collection_snippets ADD CONSTRAINT collection_snippets_fk_snippet_id FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE;
+
+CREATE TABLE templates (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    user_id INTEGER NULL,
+    name VARCHAR(100) NOT NULL,
+    title VARCHAR(100) NOT NULL,
+    content MEDIUMTEXT NOT NULL,
+    language VARCHAR(30) NOT NULL DEFAULT '',
+    format ENUM('plain', 'code', 'markdown') NOT NULL DEFAULT 'code',
+    expires VARCHAR(10) NOT NULL,
+    expires_amount INTEGER NOT NULL DEFAULT 0,
+    expires_unit VARCHAR(10) NOT NULL DEFAULT '',
+    created DATETIME NOT NULL,
+    modified DATETIME NOT NULL
+);
+
+ALTER TABLE templates ADD CONSTRAINT templates_fk_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE TABLE tags (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    name VARCHAR(30) NOT NULL
+);
+
+ALTER TABLE tags ADD CONSTRAINT tags_uc_name UNIQUE (name);
+
+CREATE TABLE snippet_tags (
+    snippet_id INTEGER NOT NULL,
+    tag_id INTEGER NOT NULL,
+    PRIMARY KEY (snippet_id, tag_id)
+);
+
+ALTER TABLE snippet_tags ADD CONSTRAINT snippet_tags_fk_snippet_id FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE;
+ALTER TABLE snippet_tags ADD CONSTRAINT snippet_tags_fk_tag_id FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE;
 
 INSERT INTO users (name, email, hashed_password, created) VALUES (
     'Alice Jones',
     'alice@example.com',
     '$2a$12$NuTjWXm3KKntReFwyBVHyuf/to.HEwTy.eS206TNfkGfr6HzGJSWG',
     '2022-01-01 10:00:00'
-);
\ No newline at end of file
+);
@@ -1,3 +1,25 @@
-DROP TABLE users;
+DROP TABLE snippet_tags;
+
+DROP TABLE tags;
+
+DROP TABLE templates;
+
+DROP TABLE collection_snippets;
+
+DROP TABLE collections;
+
+DROP TABLE snippet_views;
+
+DROP TABLE stars;
 
-DROP TABLE snippets;
\ No newline at end of file
+DROP TABLE comments;
+
+DROP TABLE snippet_revision_files;
+
+DROP TABLE snippet_files;
+
+DROP TABLE snippet_revisions;
+
+DROP TABLE snippets;
+
+DROP TABLE users;
@@ -18,6 +18,7 @@ type User struct {
 	Email          string
 	HashedPassword []byte
 	Created        time.Time
+	Admin          bool
 }
 
 type UserModelInterface interface {
@@ -112,16 +113,18 @@ func (m *UserModel) Exists(id int) (bool, error) {
 
 }
 
+// We'll use the GetbyID method to fetch the details of a specific user. If no
+// user with that ID exists we return the ErrNoRecord error.
 func (m *UserModel) GetbyID(id int) (*User, error) {
 
 	user := &User{}
 
-	stmt := "SELECT name, email, created FROM users WHERE id = ?"
+	stmt := "SELECT id, name, email, created, admin FROM users WHERE id = ?"
 
-	err := m.DB.QueryRow(stmt, id).Scan(&user.Name, &user.Email, &user.Created)
+	err := m.DB.QueryRow(stmt, id).Scan(&user.ID, &user.Name, &user.Email, &user.Created, &user.Admin)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrInvalidCredentials
+			return nil, ErrNoRecord
 		} else {
 			return nil, err
 		}
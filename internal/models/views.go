@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+)
+
+// ViewKey identifies the views of one snippet on one UTC day.
+type ViewKey struct {
+	SnippetID int
+	Day       time.Time // midnight UTC
+}
+
+// DailyViews is the number of times a snippet was viewed on one day.
+type DailyViews struct {
+	Day   time.Time
+	Views int
+}
+
+// This will add buffered view counts to the snippet_views table, in a single
+// transaction. Counts for snippets that have since been purged are dropped.
+func (m *SnippetModel) AddViews(views map[ViewKey]int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := `INSERT INTO snippet_views (snippet_id, day, views)
+    SELECT id, ?, ? FROM snippets WHERE id = ?
+    ON DUPLICATE KEY UPDATE views = views + VALUES(views)`
+
+	for key, n := range views {
+		_, err = tx.Exec(stmt, key.Day.UTC().Format("2006-01-02"), n, key.SnippetID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// This will return the daily view counts of a snippet from the given day on,
+// oldest first. Days without any views are left out.
+func (m *SnippetModel) DailyViews(snippetID int, since time.Time) ([]*DailyViews, error) {
+	stmt := `SELECT day, views FROM snippet_views
+    WHERE snippet_id = ? AND day >= ? ORDER BY day`
+
+	rows, err := m.DB.Query(stmt, snippetID, since.UTC().Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	views := []*DailyViews{}
+
+	for rows.Next() {
+		v := &DailyViews{}
+
+		err = rows.Scan(&v.Day, &v.Views)
+		if err != nil {
+			return nil, err
+		}
+
+		views = append(views, v)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return views, nil
+}
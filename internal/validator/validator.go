@@ -13,6 +13,15 @@ import (
 // variable is more performant than re-parsing the pattern each time we need it.
 var EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 
+// TagRX matches a single snippet tag: up to 30 lowercase letters, digits and
+// the characters "+", "#", "." and "-", starting with a letter or digit (so
+// that tags like "c++", "c#" and "node.js" work).
+var TagRX = regexp.MustCompile(`^[a-z0-9][a-z0-9+#.-]{0,29}$`)
+
+// FileNameRX matches the name of a file in a snippet: up to 100 letters,
+// digits and the characters ".", "_" and "-", not starting with a dot.
+var FileNameRX = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]{0,99}$`)
+
 // Define a new Validator type which contains a map of validation errors for our
 // form fields.
 type Validator struct {
@@ -63,6 +72,12 @@ func MaxChars(value string, n int) bool {
 	return utf8.RuneCountInString(value) <= n
 }
 
+// MaxBytes() returns true if a value is no more than n bytes long once encoded
+// as UTF-8, which is how it's stored.
+func MaxBytes(value string, n int) bool {
+	return len(value) <= n
+}
+
 // PermittedInt() returns true if a value is in a list of permitted integers.
 func PermittedInt(value int, permittedValues ...int) bool {
 	for i := range permittedValues {
@@ -95,3 +110,19 @@ func MinChars(value string, n int) bool {
 func Matches(value string, rx *regexp.Regexp) bool {
 	return rx.MatchString(value)
 }
+
+// AllMatch() returns true if every value matches a provided compiled regular
+// expression pattern.
+func AllMatch(values []string, rx *regexp.Regexp) bool {
+	for _, value := range values {
+		if !rx.MatchString(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// MaxItems() returns true if a slice contains no more than n items.
+func MaxItems[T any](values []T, n int) bool {
+	return len(values) <= n
+}
@@ -5,4 +5,13 @@ for (var i = 0; i < navLinks.length; i++) {
 		link.classList.add("live");
 		break;
 	}
-}
\ No newline at end of file
+}
+var addFile = document.getElementById("add-file");
+if (addFile) {
+	addFile.addEventListener("click", function() {
+		var files = document.getElementById("files");
+		var n = files.querySelectorAll("div.file").length;
+		var row = document.getElementById("file-row").innerHTML.replace(/__N__/g, n);
+		files.insertAdjacentHTML("beforeend", row);
+	});
+}